@@ -0,0 +1,92 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// VerticalAlign selects how badge text is aligned vertically to the icon.
+type VerticalAlign int
+
+const (
+	// AlignMiddle centers the text's x-height on the icon's vertical center,
+	// which reads as optically centered for mixed-case text.
+	AlignMiddle VerticalAlign = iota
+	// AlignTop aligns the top of the text's line box with the icon's top.
+	AlignTop
+	// AlignBaseline sits the text's baseline on the icon's bottom edge.
+	AlignBaseline
+)
+
+// BadgeOptions configures ApplyBadge.
+type BadgeOptions struct {
+	// Options places the badge as a whole, like any other watermark.
+	Options
+	// Text configures how the text is rendered.
+	Text TextOptions
+	// Spacing is the gap in pixels between the icon and the text.
+	Spacing int
+	// Plate, if set, fills the badge background behind the icon and text.
+	Plate color.Color
+	// PlatePadding is the margin in pixels between the plate edge and the
+	// icon and text.
+	PlatePadding int
+	// Align selects how the text is aligned vertically to the icon.
+	Align VerticalAlign
+}
+
+// DefaultBadgeOptions returns sensible badge defaults.
+func DefaultBadgeOptions() BadgeOptions {
+	return BadgeOptions{
+		Options:      DefaultOptions(),
+		Spacing:      6,
+		PlatePadding: 4,
+	}
+}
+
+// ApplyBadge stamps a small "contact info" badge: icon followed by text,
+// side by side and aligned according to opts.Align, on an optional plate,
+// placed according to opts.Options.
+func ApplyBadge(src, icon image.Image, text string, opts BadgeOptions) image.Image {
+	return Apply(src, renderBadge(icon, text, opts), opts.Options)
+}
+
+// renderBadge lays out the icon and text into a single watermark image.
+func renderBadge(icon image.Image, text string, opts BadgeOptions) *image.RGBA {
+	label := renderText(text, opts.Text)
+	iconSize := icon.Bounds().Size()
+	labelSize := label.Bounds().Size()
+
+	// Lay out vertically with the icon's top at zero, then shift
+	// everything down so the topmost element touches the padding.
+	var labelY int
+	switch opts.Align {
+	case AlignMiddle:
+		ascent := opts.Text.ascent().Ceil()
+		labelY = iconSize.Y/2 - ascent + xHeight(opts.Text.face())/2
+	case AlignBaseline:
+		labelY = iconSize.Y - opts.Text.ascent().Ceil()
+	}
+	top := 0
+	if labelY < top {
+		top = labelY
+	}
+	bottom := iconSize.Y
+	if labelY+labelSize.Y > bottom {
+		bottom = labelY + labelSize.Y
+	}
+
+	pad := opts.PlatePadding
+	w := iconSize.X + opts.Spacing + labelSize.X + 2*pad
+	h := bottom - top + 2*pad
+
+	badge := image.NewRGBA(image.Rect(0, 0, w, h))
+	if opts.Plate != nil {
+		draw.Draw(badge, badge.Bounds(), image.NewUniform(opts.Plate), image.Point{}, draw.Src)
+	}
+
+	drawOver(badge, icon, image.Pt(pad, pad-top))
+	drawOver(badge, label, image.Pt(pad+iconSize.X+opts.Spacing, pad-top+labelY))
+	return badge
+}
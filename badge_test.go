@@ -0,0 +1,90 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// colorBounds returns the bounding box of the pixels of img that match.
+func colorBounds(img image.Image, match func(c color.RGBA) bool) image.Rectangle {
+	var r image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if match(rgbaAt(img, x, y)) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestApplyBadge(t *testing.T) {
+	src := fill(200, 100, color.Black)
+	icon := fill(12, 12, color.RGBA{255, 0, 0, 255})
+	opts := BadgeOptions{
+		Options: Options{Position: BottomRight, Opacity: 1, PaddingX: 8, PaddingY: 8},
+		Spacing: 4,
+	}
+	out := ApplyBadge(src, icon, "hi", opts)
+
+	red := colorBounds(out, func(c color.RGBA) bool { return c.R > 200 && c.G < 50 })
+	text := colorBounds(out, func(c color.RGBA) bool { return c.G > 200 })
+	if red.Empty() || text.Empty() {
+		t.Fatalf("icon at %v, text at %v: want both drawn", red, text)
+	}
+
+	// Icon then text, side by side, in the bottom-right corner.
+	if gap := text.Min.X - red.Max.X; gap < opts.Spacing || gap > opts.Spacing+2 {
+		t.Errorf("gap between icon %v and text %v = %d, want about %d", red, text, gap, opts.Spacing)
+	}
+	if !red.Overlaps(image.Rect(0, text.Min.Y, 200, text.Max.Y)) {
+		t.Errorf("icon %v and text %v do not share any rows", red, text)
+	}
+	if corner := image.Rect(100, 50, 200, 100); !red.In(corner) || !text.In(corner) {
+		t.Errorf("icon %v and text %v, want both in the bottom-right corner", red, text)
+	}
+	if right := src.Bounds().Dx() - opts.PaddingX; text.Max.X > right {
+		t.Errorf("text ends at x=%d, beyond the padding at %d", text.Max.X, right)
+	}
+}
+
+func TestApplyBadgePlate(t *testing.T) {
+	src := fill(200, 100, color.Black)
+	opts := DefaultBadgeOptions()
+	opts.Opacity = 1
+	opts.Plate = color.RGBA{0, 0, 255, 255}
+	out := ApplyBadge(src, fill(12, 12, color.RGBA{255, 0, 0, 255}), "hi", opts)
+
+	plate := colorBounds(out, func(c color.RGBA) bool { return c.B > 200 })
+	red := colorBounds(out, func(c color.RGBA) bool { return c.R > 200 && c.G < 50 })
+	if !red.In(plate) || red.Min.X-plate.Min.X != opts.PlatePadding {
+		t.Errorf("icon %v, want inset %dpx into plate %v", red, opts.PlatePadding, plate)
+	}
+}
+
+func TestBadgeAlign(t *testing.T) {
+	icon := fill(12, 30, color.RGBA{255, 0, 0, 255})
+	isIcon := func(c color.RGBA) bool { return c.R > 200 && c.G < 50 }
+	isText := func(c color.RGBA) bool { return c.G > 100 }
+
+	// A lone "x" covers exactly the x-height above the baseline.
+	middle := renderBadge(icon, "x", BadgeOptions{Align: AlignMiddle})
+	red, x := colorBounds(middle, isIcon), colorBounds(middle, isText)
+	if d := (x.Min.Y + x.Max.Y) - (red.Min.Y + red.Max.Y); d < -4 || d > 4 {
+		t.Errorf("middle: x-height %v centered %.1fpx off icon %v", x, float64(d)/2, red)
+	}
+
+	baseline := renderBadge(icon, "x", BadgeOptions{Align: AlignBaseline})
+	red, x = colorBounds(baseline, isIcon), colorBounds(baseline, isText)
+	if x.Max.Y != red.Max.Y {
+		t.Errorf("baseline: text bottom %d, want icon bottom %d", x.Max.Y, red.Max.Y)
+	}
+
+	top := renderBadge(icon, "x", BadgeOptions{Align: AlignTop})
+	red = colorBounds(top, isIcon)
+	if red.Min.Y != 0 || top.Bounds().Dy() != 30 {
+		t.Errorf("top: icon at %v in a %v badge, want both from the top edge", red, top.Bounds())
+	}
+}
@@ -0,0 +1,182 @@
+package watermark
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ApplyBatch watermarks each of srcs as Apply does, concurrently on up to
+// GOMAXPROCS goroutines. The scaled and otherwise prepared watermark is
+// computed once per distinct output size and shared read-only between
+// workers, so batches of same-sized images pay for it only once. The
+// results are in the same order as srcs.
+func ApplyBatch(srcs []image.Image, watermark image.Image, opts Options) []image.Image {
+	out := make([]image.Image, len(srcs))
+	cache := newPreparedCache(watermark, opts)
+	parallel(len(srcs), func(i int) {
+		out[i] = cache.apply(srcs[i])
+	})
+	return out
+}
+
+// ApplyFilesToDir watermarks the image at each of srcPaths and saves it
+// under the same file name in outDir, which is created if needed, in the
+// format implied by the extension and declaring opts.OutputDPI if set. The
+// watermark is loaded once, as ApplyFromFiles loads it, and prepared once
+// per distinct source size; files are processed concurrently as ApplyBatch
+// does. Sources sharing a file name would overwrite each other's output, so
+// they are rejected with ErrDuplicateOutput before any file is processed,
+// and an outDir holding a source file is rejected with ErrOutputIsSource.
+// A failure does not stop the other files: the errors of all failed files
+// are returned together as a BatchError.
+func ApplyFilesToDir(srcPaths []string, outDir, watermarkPath string, opts Options, quality int) error {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]string, len(srcPaths))
+	for _, path := range srcPaths {
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if dir == absOut {
+			return fmt.Errorf("%s: %w", path, ErrOutputIsSource)
+		}
+		name := filepath.Base(path)
+		if prev, ok := seen[name]; ok {
+			return fmt.Errorf("%s, %s: %w", prev, path, ErrDuplicateOutput)
+		}
+		seen[name] = path
+	}
+
+	wm, err := loadWatermark(watermarkPath, &opts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+
+	cache := newPreparedCache(wm, opts)
+	errs := make([]error, len(srcPaths))
+	parallel(len(srcPaths), func(i int) {
+		errs[i] = applyFileToDir(srcPaths[i], outDir, cache, quality)
+	})
+
+	var batch BatchError
+	for _, err := range errs {
+		if err != nil {
+			batch = append(batch, err)
+		}
+	}
+	if batch != nil {
+		return batch
+	}
+	return nil
+}
+
+// applyFileToDir watermarks one file for ApplyFilesToDir, naming the file
+// in any error.
+func applyFileToDir(path, outDir string, cache *preparedCache, quality int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	src, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	out := filepath.Join(outDir, filepath.Base(path))
+	if err := saveFile(cache.apply(src), out, quality, cache.opts.OutputDPI); err != nil {
+		return fmt.Errorf("%s: %w", out, err)
+	}
+	return nil
+}
+
+// BatchError holds the errors of the files that failed in a batch, in the
+// order the files were given.
+type BatchError []error
+
+func (e BatchError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of the failed files, so errors.Is and errors.As
+// match any of them.
+func (e BatchError) Unwrap() []error {
+	return e
+}
+
+// parallel calls fn for each index below n on up to GOMAXPROCS goroutines
+// and waits for all calls to return.
+func parallel(n int, fn func(i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// preparedCache holds a watermark prepared for each output size it has been
+// requested at. It is safe for concurrent use.
+type preparedCache struct {
+	watermark image.Image
+	opts      Options
+
+	mu    sync.Mutex
+	sizes map[image.Point]image.Image
+}
+
+func newPreparedCache(watermark image.Image, opts Options) *preparedCache {
+	return &preparedCache{watermark: watermark, opts: opts, sizes: make(map[image.Point]image.Image)}
+}
+
+// get returns the watermark prepared at size, preparing it on first use.
+func (c *preparedCache) get(size image.Point) image.Image {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if wm, ok := c.sizes[size]; ok {
+		return wm
+	}
+	wm := prepareWatermark(c.watermark, size, c.opts)
+	c.sizes[size] = wm
+	return wm
+}
+
+// apply returns a watermarked copy of src, as Apply would. Watermarks tinted
+// to each source by AutoComplementTint cannot be shared and are prepared
+// per call.
+func (c *preparedCache) apply(src image.Image) image.Image {
+	if c.opts.AutoComplementTint {
+		return Apply(src, c.watermark, c.opts)
+	}
+	r := c.opts.Resolve(src.Bounds(), c.watermark.Bounds())
+	return applyPrepared(src, c.watermark, c.get(r.Rect.Size()), r, c.opts)
+}
@@ -0,0 +1,174 @@
+package watermark
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestApplyBatch shares prepared watermarks between workers; run it under
+// the race detector with go test -race.
+func TestApplyBatch(t *testing.T) {
+	wm := photo(40, 20)
+	opts := Options{Position: BottomRight, Opacity: 0.7, Scale: 0.25, PaddingX: 4, PaddingY: 4}
+
+	// Repeated sizes so workers reuse the same prepared watermark.
+	var srcs []image.Image
+	for i := 0; i < 24; i++ {
+		srcs = append(srcs, photo(120+40*(i%3), 90))
+	}
+	out := ApplyBatch(srcs, wm, opts)
+	if len(out) != len(srcs) {
+		t.Fatalf("%d results for %d sources", len(out), len(srcs))
+	}
+	for i, src := range srcs {
+		want := Apply(src, wm, opts)
+		if out[i].Bounds() != want.Bounds() {
+			t.Errorf("result %d: bounds %v, want %v", i, out[i].Bounds(), want.Bounds())
+			continue
+		}
+		if r := changed(out[i], want); !r.Empty() {
+			t.Errorf("result %d differs from Apply in %v", i, r)
+		}
+	}
+}
+
+func BenchmarkApplyBatch(b *testing.B) {
+	// A large logo scaled down, so preparing it is a real cost.
+	wm := photo(1200, 600)
+	opts := Options{Position: BottomRight, Opacity: 0.7, Scale: 0.25}
+	srcs := make([]image.Image, 16)
+	for i := range srcs {
+		srcs[i] = photo(800, 600)
+	}
+
+	b.Run("batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ApplyBatch(srcs, wm, opts)
+		}
+	})
+	b.Run("serial", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, src := range srcs {
+				Apply(src, wm, opts)
+			}
+		}
+	})
+}
+
+func TestApplyFilesToDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	mark := write("mark.png", pngBytes(t, photo(40, 20)))
+	opts := Options{Position: BottomRight, Opacity: 0.7, Scale: 0.25, PaddingX: 4, PaddingY: 4}
+
+	// Files of two sizes.
+	srcs := map[string]image.Image{}
+	var paths []string
+	for i, size := range []image.Point{{120, 90}, {200, 150}, {120, 90}, {200, 150}} {
+		img := photo(size.X, size.Y)
+		name := fmt.Sprintf("src%d.png", i)
+		srcs[name] = img
+		paths = append(paths, write(name, pngBytes(t, img)))
+	}
+	out := filepath.Join(dir, "out")
+	if err := ApplyFilesToDir(paths, out, mark, opts, 90); err != nil {
+		t.Fatal(err)
+	}
+	wm := photo(40, 20)
+	for name, src := range srcs {
+		f, err := os.Open(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := Apply(src, wm, opts)
+		if got.Bounds() != want.Bounds() {
+			t.Errorf("%s: bounds %v, want %v", name, got.Bounds(), want.Bounds())
+			continue
+		}
+		if r := changed(got, want); !r.Empty() {
+			t.Errorf("%s: differs from Apply in %v", name, r)
+		}
+	}
+
+	// Files that fail do not stop the others.
+	broken := write("broken.png", []byte("not a png"))
+	missing := filepath.Join(dir, "missing.png")
+	err := ApplyFilesToDir([]string{broken, paths[0], missing}, filepath.Join(dir, "partial"), mark, opts, 90)
+	if batch, ok := err.(BatchError); !ok || len(batch) != 2 {
+		t.Errorf("err = %v, want a BatchError for the broken and missing files", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("errors.Is(%v, os.ErrNotExist) = false, want the missing file's error to match", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "partial", "src0.png")); err != nil {
+		t.Errorf("good file not written: %v", err)
+	}
+}
+
+func TestApplyFilesToDirDuplicateNames(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, sub := range []string{"a", "b"} {
+		path := filepath.Join(dir, sub, "photo.png")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, pngBytes(t, photo(20, 20)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	out := filepath.Join(dir, "out")
+	err := ApplyFilesToDir(paths, out, filepath.Join(dir, "missing.png"), DefaultOptions(), 90)
+	if !errors.Is(err, ErrDuplicateOutput) {
+		t.Errorf("err = %v, want ErrDuplicateOutput", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("output directory was created for a rejected batch")
+	}
+}
+
+func TestApplyFilesToDirSourceDir(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.png")
+	if err := os.WriteFile(path, pngBytes(t, photo(20, 20)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same directory spelled differently is still rejected.
+	out := filepath.Join(dir, "sub", "..")
+	err = ApplyFilesToDir([]string{path}, out, filepath.Join(dir, "missing.png"), DefaultOptions(), 90)
+	if !errors.Is(err, ErrOutputIsSource) {
+		t.Errorf("err = %v, want ErrOutputIsSource", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, orig) {
+		t.Errorf("source file was modified")
+	}
+}
@@ -0,0 +1,28 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+)
+
+// ApplyColorBlock blends a solid fill color over rect, such as a redaction
+// bar or a color band. Opacity is clamped to [0, 1]; pixels outside rect are
+// left unchanged.
+func ApplyColorBlock(src image.Image, rect image.Rectangle, fill color.Color, opacity float64) image.Image {
+	if opacity < 0 {
+		opacity = 0
+	}
+	if opacity > 1 {
+		opacity = 1
+	}
+
+	dst := newCanvas(src)
+	blend := blenderFor(dst, BlendNormal)
+	area := rect.Intersect(dst.Bounds())
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			dst.Set(x, y, blend(dst.At(x, y), fill, opacity))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,20 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyColorBlock(t *testing.T) {
+	src := fill(50, 50, color.RGBA{0, 0, 200, 255})
+	rect := image.Rect(10, 10, 30, 20)
+	out := ApplyColorBlock(src, rect, color.RGBA{255, 0, 0, 255}, 0.5)
+
+	if c := rgbaAt(out, 15, 15); c != (color.RGBA{128, 0, 100, 255}) {
+		t.Errorf("block pixel = %v, want halfway to red", c)
+	}
+	if r := changed(src, out); r != rect {
+		t.Errorf("changed region = %v, want %v", r, rect)
+	}
+}
@@ -0,0 +1,191 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// gaussianKernel returns a normalized 1D Gaussian kernel spanning
+// 2*radius+1 taps, with sigma chosen so the tails fall near zero at the
+// kernel edge.
+func gaussianKernel(radius int) []float64 {
+	sigma := float64(radius) / 2
+	if sigma < 0.5 {
+		sigma = 0.5
+	}
+	kernel := make([]float64, 2*radius+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-(d * d) / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	return kernel
+}
+
+// blurPlane applies a separable Gaussian blur to a w*h plane of samples.
+// Samples outside the plane are treated as zero, so content fades out
+// towards the edges, unless clamp is set, in which case the nearest edge
+// sample is repeated instead.
+func blurPlane(plane []float64, w, h, radius int, clamp bool) []float64 {
+	kernel := gaussianKernel(radius)
+	tmp := make([]float64, len(plane))
+	out := make([]float64, len(plane))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float64
+			for k, kv := range kernel {
+				sx := x + k - radius
+				if sx < 0 || sx >= w {
+					if !clamp {
+						continue
+					}
+					sx = clampInt(sx, 0, w-1)
+				}
+				v += plane[y*w+sx] * kv
+			}
+			tmp[y*w+x] = v
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float64
+			for k, kv := range kernel {
+				sy := y + k - radius
+				if sy < 0 || sy >= h {
+					if !clamp {
+						continue
+					}
+					sy = clampInt(sy, 0, h-1)
+				}
+				v += tmp[sy*w+x] * kv
+			}
+			out[y*w+x] = v
+		}
+	}
+	return out
+}
+
+// featherAlpha returns a copy of img whose alpha channel has been blurred by
+// radius pixels. Color channels are left untouched, so only the edges of the
+// watermark soften. Fully transparent pixels have no color of their own, so
+// those the blur makes visible take the alpha-weighted average color of
+// their neighbourhood rather than showing as a dark fringe.
+func featherAlpha(img image.Image, radius int) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	// Planes 0-2 hold premultiplied color and plane 3 alpha.
+	w, h := b.Dx(), b.Dy()
+	planes := [4][]float64{}
+	for c := range planes {
+		planes[c] = make([]float64, w*h)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := dst.Pix[y*dst.Stride+x*4:]
+			a := float64(p[3])
+			i := y*w + x
+			planes[0][i], planes[1][i], planes[2][i], planes[3][i] = float64(p[0])*a, float64(p[1])*a, float64(p[2])*a, a
+		}
+	}
+
+	for c := range planes {
+		planes[c] = blurPlane(planes[c], w, h, radius, false)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := dst.Pix[y*dst.Stride+x*4:]
+			i := y*w + x
+			a := planes[3][i]
+			if p[3] == 0 && a > 0 {
+				for c := 0; c < 3; c++ {
+					p[c] = uint8(clampInt(int(math.Round(planes[c][i]/a)), 0, 255))
+				}
+			}
+			p[3] = uint8(math.Round(a))
+		}
+	}
+	return dst
+}
+
+// blurRegion Gaussian-blurs the part of dst inside r in place. Pixels outside
+// r are neither read nor written.
+func blurRegion(dst draw.Image, r image.Rectangle, radius int) {
+	r = r.Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+
+	w, h := r.Dx(), r.Dy()
+	planes := [4][]float64{}
+	for c := range planes {
+		planes[c] = make([]float64, w*h)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cr, cg, cb, ca := dst.At(r.Min.X+x, r.Min.Y+y).RGBA()
+			i := y*w + x
+			planes[0][i], planes[1][i], planes[2][i], planes[3][i] = float64(cr), float64(cg), float64(cb), float64(ca)
+		}
+	}
+
+	for c := range planes {
+		planes[c] = blurPlane(planes[c], w, h, radius, true)
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			dst.Set(r.Min.X+x, r.Min.Y+y, color.RGBA64{
+				R: uint16(math.Round(planes[0][i])),
+				G: uint16(math.Round(planes[1][i])),
+				B: uint16(math.Round(planes[2][i])),
+				A: uint16(math.Round(planes[3][i])),
+			})
+		}
+	}
+}
+
+// clampInt limits v to the range [lo, hi].
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// unsharpMask sharpens img in place by adding amount times the difference
+// between it and a Gaussian blur of the given radius.
+func unsharpMask(img *image.RGBA, radius int, amount float64) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	plane := make([]float64, w*h)
+
+	for c := 0; c < 3; c++ {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				plane[y*w+x] = float64(img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y)+c])
+			}
+		}
+		blurred := blurPlane(plane, w, h, radius, true)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				off := img.PixOffset(b.Min.X+x, b.Min.Y+y)
+				v := plane[y*w+x] + amount*(plane[y*w+x]-blurred[y*w+x])
+				// Keep the result a valid premultiplied color.
+				img.Pix[off+c] = uint8(math.Round(math.Max(0, math.Min(v, float64(img.Pix[off+3])))))
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestFeatherWidensEdge(t *testing.T) {
+	// An opaque red square with a hard edge in a transparent margin.
+	wm := image.NewNRGBA(image.Rect(0, 0, 60, 60))
+	draw.Draw(wm, image.Rect(20, 20, 40, 40), image.NewUniform(color.NRGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+
+	// band counts the partly transparent pixels across the middle row.
+	band := func(img *image.NRGBA) int {
+		n := 0
+		for x := 0; x < 60; x++ {
+			if a := img.NRGBAAt(x, 30).A; a > 0 && a < 255 {
+				n++
+			}
+		}
+		return n
+	}
+
+	prev := band(wm)
+	if prev != 0 {
+		t.Fatalf("unfeathered band = %d, want 0", prev)
+	}
+	for _, radius := range []int{1, 3, 6} {
+		feathered := featherAlpha(wm, radius)
+		got := band(feathered)
+		if got <= prev {
+			t.Errorf("Feather %d: band = %d, want wider than %d", radius, got, prev)
+		}
+		prev = got
+
+		// Only alpha is blurred: the color stays red, including in the
+		// newly visible fringe.
+		for x := 0; x < 60; x++ {
+			if c := feathered.NRGBAAt(x, 30); c.A > 0 && (c.R != 255 || c.G != 0 || c.B != 0) {
+				t.Errorf("Feather %d: color at x=%d = %v, want red", radius, x, c)
+			}
+		}
+	}
+}
+
+func TestFeatherOption(t *testing.T) {
+	src := fill(100, 100, color.Black)
+	wm := fill(40, 40, color.White)
+	opts := Options{Position: Center, Opacity: 1}
+
+	hard := Apply(src, wm, opts)
+	opts.Feather = 4
+	soft := Apply(src, wm, opts)
+
+	// The hard edge is crisp; the feathered one fades out across it.
+	if c := rgbaAt(hard, 30, 50); c.R != 255 {
+		t.Errorf("unfeathered edge = %v, want white", c)
+	}
+	if c := rgbaAt(soft, 30, 50); c.R == 0 || c.R == 255 {
+		t.Errorf("feathered edge = %v, want partly white", c)
+	}
+	if c := rgbaAt(soft, 50, 50); c.R != 255 {
+		t.Errorf("feathered center = %v, want white", c)
+	}
+}
+
+func TestBackdropBlur(t *testing.T) {
+	// A one-pixel checkerboard is all high-frequency detail.
+	src := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			if (x+y)%2 == 0 {
+				src.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+			} else {
+				src.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+	// A transparent watermark leaves only the backdrop visible.
+	wm := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	opts := Options{Position: Center, BackdropBlur: 3}
+	out := Apply(src, wm, opts)
+
+	// detail sums the differences between horizontal neighbours in r.
+	detail := func(img image.Image, r image.Rectangle) int {
+		sum := 0
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X-1; x++ {
+				d := int(rgbaAt(img, x, y).R) - int(rgbaAt(img, x+1, y).R)
+				if d < 0 {
+					d = -d
+				}
+				sum += d
+			}
+		}
+		return sum
+	}
+	under := image.Rect(40, 40, 60, 60)
+	if before, after := detail(src, under), detail(out, under); after > before/10 {
+		t.Errorf("detail under watermark = %d, want well below %d", after, before)
+	}
+
+	footprint := under.Inset(-opts.BackdropBlur)
+	if r := changed(src, out); !r.In(footprint) {
+		t.Errorf("changed region %v extends beyond footprint %v", r, footprint)
+	}
+}
@@ -0,0 +1,50 @@
+package watermark
+
+import (
+	"image"
+	"image/draw"
+)
+
+// TimedCaption is a line of text shown on a range of frames, like a WebVTT
+// cue measured in frames rather than time.
+type TimedCaption struct {
+	Text string
+	// Start is the index of the first frame the caption is shown on.
+	Start int
+	// End is the index of the frame after the last one the caption is
+	// shown on.
+	End int
+}
+
+// ApplyCaptions burns captions onto a sequence of frames, such as frames
+// exported from a video. Each frame shows every caption whose range covers
+// its index, centered near the bottom edge. When ranges overlap the
+// captions stack upwards in the order they are given. Frames without a
+// caption are returned as is; the others are copies.
+func ApplyCaptions(frames []image.Image, captions []TimedCaption, topts TextOptions) []image.Image {
+	out := make([]image.Image, len(frames))
+	for i, frame := range frames {
+		out[i] = frame
+
+		var dst draw.Image
+		b := frame.Bounds()
+		bottom := b.Max.Y - b.Dy()/20
+		for _, c := range captions {
+			if i < c.Start || i >= c.End {
+				continue
+			}
+			if dst == nil {
+				dst = newCanvas(frame)
+			}
+			label := renderText(c.Text, topts)
+			size := label.Bounds().Size()
+			bottom -= size.Y
+			drawOver(dst, label, image.Pt(b.Min.X+halfFloor(b.Dx()-size.X), bottom))
+			bottom -= size.Y / 4
+		}
+		if dst != nil {
+			out[i] = dst
+		}
+	}
+	return out
+}
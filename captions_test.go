@@ -0,0 +1,64 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyCaptions(t *testing.T) {
+	frames := make([]image.Image, 6)
+	for i := range frames {
+		frames[i] = fill(160, 90, color.Black)
+	}
+	captions := []TimedCaption{
+		{Text: "Hi", Start: 1, End: 4},
+		{Text: "Hello there", Start: 3, End: 6},
+	}
+	out := ApplyCaptions(frames, captions, TextOptions{})
+
+	isInk := func(c color.RGBA) bool { return c.R > 0 }
+	// inkWidth is the width of text's ink as rendered on its own.
+	inkWidth := func(text string) int { return colorBounds(renderText(text, TextOptions{}), isInk).Dx() }
+
+	// lines returns the ink width of each band of rows with ink in img,
+	// top to bottom.
+	lines := func(img image.Image) []int {
+		var widths []int
+		var band image.Rectangle
+		for y := 0; y <= 90; y++ {
+			var row image.Rectangle
+			for x := 0; y < 90 && x < 160; x++ {
+				if isInk(rgbaAt(img, x, y)) {
+					row = row.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+			if row.Empty() && !band.Empty() {
+				widths = append(widths, band.Dx())
+			}
+			band = band.Union(row)
+			if row.Empty() {
+				band = image.Rectangle{}
+			}
+		}
+		return widths
+	}
+
+	hi, hello := inkWidth("Hi"), inkWidth("Hello there")
+	want := [][]int{nil, {hi}, {hi}, {hello, hi}, {hello}, {hello}}
+	for i, img := range out {
+		got := lines(img)
+		if len(got) != len(want[i]) {
+			t.Errorf("frame %d: %d caption lines, want %d", i, len(got), len(want[i]))
+			continue
+		}
+		for j := range got {
+			if got[j] != want[i][j] {
+				t.Errorf("frame %d: line %d is %dpx wide, want %d", i, j, got[j], want[i][j])
+			}
+		}
+	}
+	if out[0] != frames[0] {
+		t.Error("frame without a caption was copied")
+	}
+}
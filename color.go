@@ -0,0 +1,249 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// dominantColor returns the most common color in img, found by bucketing
+// opaque-enough pixels into a coarse 4-bit-per-channel histogram and
+// averaging the fullest bucket.
+func dominantColor(img image.Image) color.RGBA {
+	type bucket struct {
+		n       int
+		r, g, b int
+	}
+	var hist [4096]bucket
+
+	b := img.Bounds()
+	best := -1
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				continue
+			}
+			i := int(c.R>>4)<<8 | int(c.G>>4)<<4 | int(c.B>>4)
+			hb := &hist[i]
+			hb.n++
+			hb.r += int(c.R)
+			hb.g += int(c.G)
+			hb.b += int(c.B)
+			if best < 0 || hb.n > hist[best].n {
+				best = i
+			}
+		}
+	}
+
+	if best < 0 {
+		return color.RGBA{A: 0xff}
+	}
+	hb := hist[best]
+	return color.RGBA{uint8(hb.r / hb.n), uint8(hb.g / hb.n), uint8(hb.b / hb.n), 0xff}
+}
+
+// contrastOverlay returns overlay with its color inverted if it is on the
+// same side of mid-gray as base, keeping its alpha.
+func contrastOverlay(base, overlay color.Color) color.Color {
+	o := color.NRGBAModel.Convert(overlay).(color.NRGBA)
+	baseDark := luma(base.RGBA()) < 128
+	overlayDark := luma(color.NRGBA{o.R, o.G, o.B, 0xff}.RGBA()) < 128
+	if baseDark == overlayDark {
+		o.R, o.G, o.B = 255-o.R, 255-o.G, 255-o.B
+	}
+	return o
+}
+
+// isGray reports whether every pixel of img has equal red, green and blue,
+// such as a grayscale logo with alpha decoded as NRGBA.
+func isGray(img image.Image) bool {
+	switch m := img.(type) {
+	case *image.Gray, *image.Gray16:
+		return true
+	case *image.NRGBA:
+		b := m.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			row := m.Pix[m.PixOffset(b.Min.X, y):m.PixOffset(b.Max.X, y)]
+			for i := 0; i < len(row); i += 4 {
+				if row[i] != row[i+1] || row[i] != row[i+2] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			if r != g || r != bl {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// neutralChroma is the chroma, the spread between the largest and smallest
+// channel as a fraction of full scale, below which complementColor treats
+// a color as a neutral gray with no meaningful hue.
+const neutralChroma = 0.1
+
+// complementColor returns c with its hue rotated by 180 degrees. Rotating
+// the hue of a neutral gray leaves it unchanged, so near-neutral colors get
+// their lightness inverted to the far end of the scale instead: white for
+// dark colors and black for light ones.
+func complementColor(c color.RGBA) color.RGBA {
+	h, s, l := rgbToHSL(c.R, c.G, c.B)
+	max := math.Max(float64(c.R), math.Max(float64(c.G), float64(c.B)))
+	min := math.Min(float64(c.R), math.Min(float64(c.G), float64(c.B)))
+	if (max-min)/255 < neutralChroma {
+		if l < 0.5 {
+			return color.RGBA{0xff, 0xff, 0xff, c.A}
+		}
+		return color.RGBA{0, 0, 0, c.A}
+	}
+	r, g, b := hslToRGB(math.Mod(h+180, 360), s, l)
+	return color.RGBA{r, g, b, c.A}
+}
+
+// tint returns a copy of img recolored to fill, keeping img's alpha so the
+// watermark's shape is preserved.
+func tint(img image.Image, fill color.Color) *image.NRGBA {
+	f := color.NRGBAModel.Convert(fill).(color.NRGBA)
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			_, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			dst.SetNRGBA(x, y, color.NRGBA{f.R, f.G, f.B, uint8(a >> 8)})
+		}
+	}
+	return dst
+}
+
+// desaturate returns a grayscale copy of img, keeping its alpha. Each
+// pixel's gray level is its Rec. 601 luma.
+func desaturate(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		y := uint8(math.Round(0.299*float64(dst.Pix[i]) + 0.587*float64(dst.Pix[i+1]) + 0.114*float64(dst.Pix[i+2])))
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = y, y, y
+	}
+	return dst
+}
+
+// hueRotateRegion rotates the hue of the pixels of dst inside r by degrees,
+// keeping their saturation, lightness and alpha. The rotation is computed
+// at 8 bits per channel.
+func hueRotateRegion(dst draw.Image, r image.Rectangle, degrees float64) {
+	r = r.Intersect(dst.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.NRGBAModel.Convert(dst.At(x, y)).(color.NRGBA)
+			h, s, l := rgbToHSL(c.R, c.G, c.B)
+			h = math.Mod(h+degrees, 360)
+			if h < 0 {
+				h += 360
+			}
+			c.R, c.G, c.B = hslToRGB(h, s, l)
+			dst.Set(x, y, c)
+		}
+	}
+}
+
+// rgbToHSL converts 8-bit RGB to hue in degrees [0, 360) and saturation and
+// lightness in [0, 1].
+func rgbToHSL(r8, g8, b8 uint8) (h, s, l float64) {
+	r, g, b := float64(r8)/255, float64(g8)/255, float64(b8)/255
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	l = (max + min) / 2
+	if max == min {
+		return 0, 0, l
+	}
+
+	d := max - min
+	if l > 0.5 {
+		s = d / (2 - max - min)
+	} else {
+		s = d / (max + min)
+	}
+	switch max {
+	case r:
+		h = math.Mod((g-b)/d+6, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// hslToRGB converts hue in degrees and saturation and lightness in [0, 1]
+// to 8-bit RGB.
+func hslToRGB(h, s, l float64) (r, g, b uint8) {
+	c := (1 - math.Abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+
+	var r1, g1, b1 float64
+	switch {
+	case hp < 1:
+		r1, g1 = c, x
+	case hp < 2:
+		r1, g1 = x, c
+	case hp < 3:
+		g1, b1 = c, x
+	case hp < 4:
+		g1, b1 = x, c
+	case hp < 5:
+		r1, b1 = x, c
+	default:
+		r1, b1 = c, x
+	}
+
+	m := l - c/2
+	to := func(v float64) uint8 { return uint8(math.Round((v + m) * 255)) }
+	return to(r1), to(g1), to(b1)
+}
+
+// ChromaKey makes pixels of img close in chroma to key transparent, for
+// logos supplied on a green or magenta key background. Distance is measured
+// on the Cb/Cr chroma plane, ignoring luma, which separates the key from
+// the logo more cleanly at its edges than plain RGB distance. Tolerance
+// is a fraction of the largest possible chroma distance (0-1); pixels just
+// beyond it fade in over half that range again for smoother edges.
+func ChromaKey(img image.Image, key color.Color, tolerance float64) image.Image {
+	kr, kg, kb, _ := key.RGBA()
+	_, kcb, kcr := color.RGBToYCbCr(uint8(kr>>8), uint8(kg>>8), uint8(kb>>8))
+
+	b := img.Bounds()
+	dst := image.NewNRGBA(b)
+	maxDist := math.Hypot(255, 255)
+	softness := tolerance / 2
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			_, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+			d := math.Hypot(float64(cb)-float64(kcb), float64(cr)-float64(kcr)) / maxDist
+
+			keep := 1.0
+			switch {
+			case d <= tolerance:
+				keep = 0
+			case d < tolerance+softness:
+				keep = (d - tolerance) / softness
+			}
+			c.A = uint8(math.Round(float64(c.A) * keep))
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,180 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestAutoComplementTint(t *testing.T) {
+	// A mostly blue photo with a little red.
+	src := fill(100, 100, color.RGBA{30, 60, 200, 255})
+	for x := 0; x < 10; x++ {
+		src.SetRGBA(x, 0, color.RGBA{255, 0, 0, 255})
+	}
+	wm := fill(20, 20, color.White)
+	out := Apply(src, wm, Options{Position: Center, Opacity: 1, AutoComplementTint: true})
+
+	c := rgbaAt(out, 50, 50)
+	h, s, _ := rgbToHSL(c.R, c.G, c.B)
+	if h < 20 || h > 50 || s < 0.5 {
+		t.Errorf("watermark color %v has hue %.0f, saturation %.2f, want orange", c, h, s)
+	}
+}
+
+func TestComplementColorNeutral(t *testing.T) {
+	tests := []struct {
+		c, want color.RGBA
+	}{
+		{color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}},
+		{color.RGBA{40, 42, 40, 255}, color.RGBA{255, 255, 255, 255}},
+		{color.RGBA{128, 128, 128, 255}, color.RGBA{0, 0, 0, 255}},
+		{color.RGBA{250, 250, 248, 255}, color.RGBA{0, 0, 0, 255}},
+	}
+	for _, tt := range tests {
+		if got := complementColor(tt.c); got != tt.want {
+			t.Errorf("complementColor(%v) = %v, want %v", tt.c, got, tt.want)
+		}
+	}
+
+	// A gray photo still gets a watermark that stands out from it.
+	src := fill(60, 60, color.RGBA{220, 220, 220, 255})
+	out := Apply(src, fill(20, 20, color.RGBA{220, 220, 220, 255}), Options{Position: Center, Opacity: 1, AutoComplementTint: true})
+	if c := rgbaAt(out, 30, 30); c.R > 50 {
+		t.Errorf("watermark on a light gray photo = %v, want dark", c)
+	}
+}
+
+func TestChromaKey(t *testing.T) {
+	green := color.RGBA{0, 200, 0, 255}
+	logo := fill(30, 30, green)
+	// A slightly different green in the corner, as from uneven lighting.
+	draw.Draw(logo, image.Rect(0, 0, 5, 5), image.NewUniform(color.RGBA{20, 180, 20, 255}), image.Point{}, draw.Src)
+	// The logo body: red with a white stripe.
+	draw.Draw(logo, image.Rect(10, 10, 20, 20), image.NewUniform(color.RGBA{220, 30, 30, 255}), image.Point{}, draw.Src)
+	draw.Draw(logo, image.Rect(10, 14, 20, 16), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	out := ChromaKey(logo, green, 0.15)
+	for _, p := range []image.Point{{25, 25}, {2, 2}} {
+		if _, _, _, a := out.At(p.X, p.Y).RGBA(); a != 0 {
+			t.Errorf("key pixel %v alpha = %d, want transparent", p, a>>8)
+		}
+	}
+	for _, p := range []image.Point{{12, 12}, {12, 15}} {
+		if got, want := rgbaAt(out, p.X, p.Y), rgbaAt(logo, p.X, p.Y); got != want {
+			t.Errorf("logo pixel %v = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestBlendContrast(t *testing.T) {
+	// Left half black, right half white.
+	src := fill(40, 20, color.Black)
+	draw.Draw(src, image.Rect(20, 0, 40, 20), image.White, image.Point{}, draw.Src)
+
+	for _, mark := range []color.Color{color.White, color.Black} {
+		out := Apply(src, fill(40, 20, mark), Options{Position: TopLeft, Opacity: 1, BlendMode: BlendContrast})
+		if c := rgbaAt(out, 5, 10); c.R < 200 {
+			t.Errorf("mark %v: over the dark half = %v, want light", mark, c)
+		}
+		if c := rgbaAt(out, 35, 10); c.R > 55 {
+			t.Errorf("mark %v: over the light half = %v, want dark", mark, c)
+		}
+	}
+}
+
+func TestBackdropHue(t *testing.T) {
+	src := fill(60, 60, color.RGBA{200, 40, 40, 255})
+	// A transparent watermark leaves only the backdrop visible.
+	wm := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	out := Apply(src, wm, Options{Position: Center, BackdropHue: 120})
+
+	under := image.Rect(20, 20, 40, 40)
+	if r := changed(src, out); r != under {
+		t.Errorf("changed region %v, want the footprint %v", r, under)
+	}
+	h0, s0, l0 := rgbToHSL(200, 40, 40)
+	c := rgbaAt(out, 30, 30)
+	h, s, l := rgbToHSL(c.R, c.G, c.B)
+	if math.Abs(h-(h0+120)) > 1 || math.Abs(s-s0) > 0.01 || math.Abs(l-l0) > 0.01 {
+		t.Errorf("backdrop HSL (%.0f, %.2f, %.2f), want (%.0f, %.2f, %.2f)", h, s, l, h0+120, s0, l0)
+	}
+}
+
+func TestGrayWatermarkOnGraySource(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 40, 30))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i % 200)
+	}
+	// A gray logo with alpha, decoded as NRGBA.
+	wm := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			wm.SetNRGBA(x, y, color.NRGBA{230, 230, 230, uint8(x * 25)})
+		}
+	}
+	if !isGray(wm) {
+		t.Fatal("isGray = false for a gray NRGBA watermark")
+	}
+	opts := Options{Position: Center, Opacity: 0.8}
+
+	out := Apply(src, wm, opts)
+	gray, ok := out.(*image.Gray)
+	if !ok {
+		t.Fatalf("output %T, want *image.Gray", out)
+	}
+	// The gray path matches compositing in color, to within rounding.
+	rgba := image.NewRGBA(src.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), src, image.Point{}, draw.Src)
+	want := Apply(rgba, wm, opts)
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			if d := int(gray.GrayAt(x, y).Y) - int(rgbaAt(want, x, y).R); d < -1 || d > 1 {
+				t.Fatalf("pixel (%d, %d) = %d, want %d", x, y, gray.GrayAt(x, y).Y, rgbaAt(want, x, y).R)
+			}
+		}
+	}
+
+	// A colored watermark needs a color canvas.
+	wm.SetNRGBA(5, 5, color.NRGBA{255, 0, 0, 255})
+	if isGray(wm) {
+		t.Error("isGray = true for a watermark with a red pixel")
+	}
+	out = Apply(src, wm, opts)
+	if _, ok := out.(*image.Gray); ok {
+		t.Error("colored watermark was composited in gray")
+	}
+	if c := rgbaAt(out, 20, 15); c.R <= c.G {
+		t.Errorf("red watermark pixel = %v, want red", c)
+	}
+}
+
+func TestDesaturate(t *testing.T) {
+	opts := Options{Desaturate: true}
+
+	// A watermark that is already gray is used as is.
+	gray := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := range gray.Pix {
+		gray.Pix[i] = 200
+	}
+	if got := prepareWatermark(gray, gray.Bounds().Size(), opts); got != image.Image(gray) {
+		t.Error("gray watermark was copied for desaturation")
+	}
+
+	red := fill(4, 4, color.RGBA{255, 0, 0, 255})
+	got := prepareWatermark(red, red.Bounds().Size(), opts)
+	if !isGray(got) {
+		t.Fatal("desaturated watermark is not gray")
+	}
+	if c := rgbaAt(got, 1, 1); c.R != 76 || c.A != 255 {
+		t.Errorf("desaturated red = %v, want luma 76 and opaque", c)
+	}
+
+	// Desaturating lets a colored watermark keep a gray source gray.
+	src := image.NewGray(image.Rect(0, 0, 10, 10))
+	if out := Apply(src, red, Options{Opacity: 1, Desaturate: true}); out.ColorModel() != color.GrayModel {
+		t.Errorf("output %T, want *image.Gray", out)
+	}
+}
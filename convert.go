@@ -0,0 +1,31 @@
+package watermark
+
+import (
+	"image"
+	"image/draw"
+)
+
+// ToNRGBA converts img to straight (non-premultiplied) alpha, as required by
+// some WebGL and video tools. Images that are already *image.NRGBA are
+// returned as is.
+func ToNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok {
+		return n
+	}
+	b := img.Bounds()
+	dst := image.NewNRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+	return dst
+}
+
+// FlattenOnto composites fg over bg with correct alpha, so transparent
+// regions of fg reveal bg rather than a single flat color. The result has
+// fg's bounds; bg is aligned to fg's top-left corner and not scaled. Use it
+// before encoding a transparent image to JPEG.
+func FlattenOnto(fg, bg image.Image) image.Image {
+	b := fg.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, bg, bg.Bounds().Min, draw.Src)
+	draw.Draw(dst, b, fg, b.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,57 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestToNRGBA(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	// Half-transparent red, premultiplied.
+	img.SetRGBA(0, 0, color.RGBA{128, 0, 0, 128})
+	img.SetRGBA(1, 0, color.RGBA{10, 20, 30, 255})
+
+	n := ToNRGBA(img)
+	if got, want := n.NRGBAAt(0, 0), (color.NRGBA{255, 0, 0, 128}); got != want {
+		t.Errorf("translucent pixel = %v, want %v", got, want)
+	}
+	if got, want := n.NRGBAAt(1, 0), (color.NRGBA{10, 20, 30, 255}); got != want {
+		t.Errorf("opaque pixel = %v, want %v", got, want)
+	}
+
+	if ToNRGBA(n) != n {
+		t.Error("ToNRGBA copied an image that is already NRGBA")
+	}
+}
+
+func TestFlattenOnto(t *testing.T) {
+	// A background with a left-to-right gradient.
+	bg := image.NewRGBA(image.Rect(0, 0, 10, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 10; x++ {
+			bg.SetRGBA(x, y, color.RGBA{uint8(x * 20), 0, 100, 255})
+		}
+	}
+	// A foreground, transparent except for an opaque white left half.
+	fg := image.NewNRGBA(image.Rect(0, 0, 10, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 5; x++ {
+			fg.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 255})
+		}
+		fg.SetNRGBA(5, y, color.NRGBA{255, 255, 255, 128})
+	}
+
+	out := FlattenOnto(fg, bg)
+	if c := rgbaAt(out, 2, 2); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("opaque foreground = %v, want white", c)
+	}
+	for x := 6; x < 10; x++ {
+		if got, want := rgbaAt(out, x, 2), bg.RGBAAt(x, 2); got != want {
+			t.Errorf("transparent foreground at x=%d = %v, want background %v", x, got, want)
+		}
+	}
+	if c := rgbaAt(out, 5, 2); c != (color.RGBA{178, 128, 178, 255}) {
+		t.Errorf("half-transparent foreground = %v, want half white over background", c)
+	}
+}
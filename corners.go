@@ -0,0 +1,62 @@
+package watermark
+
+import (
+	"image"
+	"image/draw"
+)
+
+// ApplyFourCorners stamps the watermark in all four corners, mirrored so each
+// copy points inward: the top-left copy is the watermark as given, the
+// top-right is flipped horizontally, the bottom-left vertically and the
+// bottom-right both ways. The Position, Anchor and RTL fields of opts are
+// ignored, since each copy's corner is fixed.
+func ApplyFourCorners(src, watermark image.Image, opts Options) image.Image {
+	return ApplyFourCornersWith(src, watermark, [4]Options{opts, opts, opts, opts})
+}
+
+// ApplyFourCornersWith is like ApplyFourCorners but takes separate options
+// per corner, in the order top-left, top-right, bottom-left, bottom-right,
+// so padding and other settings can be tuned for each corner. As there,
+// Position, Anchor and RTL are ignored.
+func ApplyFourCornersWith(src, watermark image.Image, opts [4]Options) image.Image {
+	flipped := flipHorizontal(watermark)
+	stamps := []struct {
+		pos Position
+		wm  image.Image
+	}{
+		{TopLeft, watermark},
+		{TopRight, flipped},
+		{BottomLeft, flipVertical(watermark)},
+		{BottomRight, flipVertical(flipped)},
+	}
+
+	dst := newCanvas(src)
+	for i, s := range stamps {
+		o := opts[i]
+		o.Position, o.Anchor, o.RTL = s.pos, nil, false
+		applyTo(dst, s.wm, o)
+	}
+	return dst
+}
+
+// flipHorizontal returns a left-right mirror image of img.
+func flipHorizontal(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.Set(b.Dx()-1-x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// flipVertical returns a top-bottom mirror image of img.
+func flipVertical(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		draw.Draw(dst, image.Rect(0, b.Dy()-1-y, b.Dx(), b.Dy()-y), img, image.Pt(b.Min.X, b.Min.Y+y), draw.Src)
+	}
+	return dst
+}
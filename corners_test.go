@@ -0,0 +1,90 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// arrow returns a 10x10 watermark, white with a red left column, so its
+// orientation can be told from the output.
+func arrow() *image.RGBA {
+	wm := fill(10, 10, color.White)
+	for y := 0; y < 10; y++ {
+		wm.SetRGBA(0, y, color.RGBA{255, 0, 0, 255})
+	}
+	return wm
+}
+
+func TestApplyFourCorners(t *testing.T) {
+	src := fill(100, 60, color.Black)
+	opts := Options{Opacity: 1, PaddingX: 5, PaddingY: 5}
+	out := ApplyFourCorners(src, arrow(), opts)
+
+	corners := []image.Rectangle{
+		image.Rect(5, 5, 15, 15),
+		image.Rect(85, 5, 95, 15),
+		image.Rect(5, 45, 15, 55),
+		image.Rect(85, 45, 95, 55),
+	}
+	for _, r := range corners {
+		if c := rgbaAt(out, r.Min.X+5, r.Min.Y+5); c.G != 255 {
+			t.Errorf("no stamp in %v", r)
+		}
+	}
+
+	// The top-right stamp mirrors the top-left one, so each red edge faces
+	// inwards.
+	tl, tr := corners[0], corners[1]
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			if a, b := rgbaAt(out, tl.Min.X+x, tl.Min.Y+y), rgbaAt(out, tr.Max.X-1-x, tr.Min.Y+y); a != b {
+				t.Fatalf("top-right (%d, %d) = %v, want mirror of top-left %v", 9-x, y, b, a)
+			}
+		}
+	}
+	if c := rgbaAt(out, tr.Max.X-1, tr.Min.Y); c.G != 0 {
+		t.Errorf("top-right outer column = %v, want red", c)
+	}
+}
+
+func TestApplyFourCornersIgnoresPlacement(t *testing.T) {
+	src := fill(100, 60, color.Black)
+	opts := Options{Opacity: 1, PaddingX: 5, PaddingY: 5}
+	want := ApplyFourCorners(src, arrow(), opts)
+
+	opts.RTL = true
+	opts.Anchor = &image.Point{40, 20}
+	if r := changed(want, ApplyFourCorners(src, arrow(), opts)); !r.Empty() {
+		t.Errorf("RTL and Anchor changed the output in %v", r)
+	}
+}
+
+func TestApplyFourCornersWith(t *testing.T) {
+	src := fill(100, 60, color.Black)
+	opts := [4]Options{
+		{Opacity: 1, PaddingX: 2, PaddingY: 3},
+		{Opacity: 1, PaddingX: 4, PaddingY: 5},
+		{Opacity: 1, PaddingX: 6, PaddingY: 7},
+		{Opacity: 1, PaddingX: 8, PaddingY: 1},
+	}
+	out := ApplyFourCornersWith(src, fill(10, 10, color.White), opts)
+
+	quadrants := []image.Rectangle{
+		image.Rect(0, 0, 50, 30),
+		image.Rect(50, 0, 100, 30),
+		image.Rect(0, 30, 50, 60),
+		image.Rect(50, 30, 100, 60),
+	}
+	want := []image.Rectangle{
+		image.Rect(2, 3, 12, 13),
+		image.Rect(86, 5, 96, 15),
+		image.Rect(6, 43, 16, 53),
+		image.Rect(82, 49, 92, 59),
+	}
+	for i, q := range quadrants {
+		if got := changed(src.SubImage(q), out.(*image.RGBA).SubImage(q)); got != want[i] {
+			t.Errorf("corner %d: stamp at %v, want %v", i, got, want[i])
+		}
+	}
+}
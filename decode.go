@@ -0,0 +1,37 @@
+package watermark
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+)
+
+// SafeDecode decodes an image from untrusted input. It rejects images whose
+// header declares more than maxPixels pixels before allocating the pixel
+// buffer, and turns decoder panics on malformed data into errors. Only the
+// header is read before the check, so oversized uploads are rejected
+// without buffering them. A maxPixels of zero or less disables the size
+// limit.
+func SafeDecode(r io.Reader, maxPixels int) (img image.Image, format string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			img, format, err = nil, "", fmt.Errorf("watermark: decoder panic: %v", p)
+		}
+	}()
+
+	// Keep the bytes DecodeConfig consumes so Decode can read them again.
+	var header bytes.Buffer
+	cfg, format, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width < 0 || cfg.Height < 0 {
+		return nil, "", ErrTooManyPixels
+	}
+	if maxPixels > 0 && int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return nil, "", ErrTooManyPixels
+	}
+
+	return image.Decode(io.MultiReader(&header, r))
+}
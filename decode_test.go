@@ -0,0 +1,117 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"testing"
+)
+
+// pngBytes returns img encoded as PNG.
+func pngBytes(t testing.TB, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := SavePNG(img, &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withSize returns PNG data whose IHDR chunk declares w x h pixels.
+func withSize(data []byte, w, h uint32) []byte {
+	out := append([]byte(nil), data...)
+	ihdr := out[len(pngSignature):]
+	binary.BigEndian.PutUint32(ihdr[8:], w)
+	binary.BigEndian.PutUint32(ihdr[12:], h)
+	binary.BigEndian.PutUint32(ihdr[21:], crc32.ChecksumIEEE(ihdr[4:21]))
+	return out
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// endless is an endless stream of zero bytes.
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestSafeDecode(t *testing.T) {
+	data := pngBytes(t, fill(20, 10, color.White))
+
+	img, format, err := SafeDecode(bytes.NewReader(data), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "png" || img.Bounds() != image.Rect(0, 0, 20, 10) {
+		t.Errorf("decoded %s %v, want png 20x10", format, img.Bounds())
+	}
+
+	if _, _, err := SafeDecode(bytes.NewReader(data), 199); err != ErrTooManyPixels {
+		t.Errorf("over the limit: error = %v, want ErrTooManyPixels", err)
+	}
+	if _, _, err := SafeDecode(bytes.NewReader(data), 0); err != nil {
+		t.Errorf("no limit: error = %v", err)
+	}
+	if _, _, err := SafeDecode(bytes.NewReader(data[:len(data)/2]), 0); err == nil {
+		t.Error("truncated data decoded without error")
+	}
+}
+
+func TestSafeDecodeRejectsBeforeReading(t *testing.T) {
+	// A header declaring 100000 x 100000 pixels followed by an endless body:
+	// it must be rejected from the header alone.
+	header := withSize(pngBytes(t, fill(1, 1, color.White)), 100000, 100000)[:len(pngSignature)+25]
+	r := &countingReader{r: io.MultiReader(bytes.NewReader(header), endless{})}
+
+	if _, _, err := SafeDecode(r, 1<<20); err != ErrTooManyPixels {
+		t.Fatalf("error = %v, want ErrTooManyPixels", err)
+	}
+	if r.n > 64<<10 {
+		t.Errorf("read %d bytes before rejecting, want only the header", r.n)
+	}
+}
+
+func FuzzSafeDecode(f *testing.F) {
+	png := pngBytes(f, fill(8, 8, color.RGBA{10, 20, 30, 255}))
+	f.Add(png)
+	f.Add(png[:len(png)/2])
+	f.Add(withSize(png, 1<<30, 1<<30))
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, fill(8, 8, color.White), nil); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(jpg.Bytes())
+	f.Add([]byte{})
+
+	const maxPixels = 1 << 16
+	f.Fuzz(func(t *testing.T, data []byte) {
+		img, _, err := SafeDecode(bytes.NewReader(data), maxPixels)
+		if err != nil {
+			if img != nil {
+				t.Errorf("error %v returned with an image", err)
+			}
+			return
+		}
+		if b := img.Bounds(); b.Dx()*b.Dy() > maxPixels {
+			t.Errorf("decoded %v, beyond the %d pixel limit", b, maxPixels)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package watermark
+
+import (
+	"image"
+	"image/draw"
+	"reflect"
+	"sync"
+)
+
+// deltaBlock is the side of the square blocks ApplyDelta compares sources
+// in.
+const deltaBlock = 32
+
+// deltaHistory is how many recent ApplyDelta results are remembered.
+const deltaHistory = 4
+
+// deltaResult is a remembered ApplyDelta call: the source it watermarked,
+// with a copy of the canvas that source was copied into and of the result.
+type deltaResult struct {
+	src, watermark image.Image
+	optsHash       string
+	base, out      draw.Image
+}
+
+// deltaResults holds the most recent ApplyDelta results, oldest first.
+var deltaResults struct {
+	mu      sync.Mutex
+	results []deltaResult
+}
+
+// ApplyDelta re-watermarks newSrc, an edited version of prevSrc, giving the
+// same result as Apply(newSrc, watermark, opts). ApplyDelta remembers its
+// last few results, so when prevSrc was the newSrc of a recent call with
+// the same watermark and options, sources are compared in blocks,
+// unchanged blocks are copied from that previous result and only changed
+// blocks are re-composited. Blocks are compared against prevSrc as it was
+// when it was watermarked, so a source edited in place may be passed as
+// both prevSrc and newSrc. Otherwise, or when AutoComplementTint makes
+// every pixel depend on the whole source, the watermark is applied in
+// full. Sources and watermarks are recognized by pointer, so other image
+// types are always applied in full.
+func ApplyDelta(prevSrc, newSrc, watermark image.Image, opts Options) image.Image {
+	b := newSrc.Bounds()
+	gray := keepsGray(newSrc, watermark, opts)
+	base := cropCanvas(newSrc, b, gray)
+	dst := blankCanvas(newSrc, b, gray)
+	key := OptionsHash(opts)
+
+	prev, ok := lookupDelta(prevSrc, watermark, key)
+	if opts.AutoComplementTint || !ok || prev.base.Bounds() != b ||
+		reflect.TypeOf(prev.base) != reflect.TypeOf(base) {
+		copyCanvas(dst, base, b)
+		applyTo(dst, watermark, opts)
+		rememberDelta(deltaResult{newSrc, watermark, key, base, cropCanvas(dst, b, gray)})
+		return dst
+	}
+	copyCanvas(dst, prev.out, b)
+
+	r := opts.Resolve(b, watermark.Bounds())
+	footprint := r.Rect
+	if opts.BackdropBlur > 0 {
+		footprint = footprint.Inset(-opts.BackdropBlur)
+	}
+	footprint = footprint.Intersect(b)
+
+	var dirty []image.Rectangle
+	for y := b.Min.Y; y < b.Max.Y; y += deltaBlock {
+		for x := b.Min.X; x < b.Max.X; x += deltaBlock {
+			t := image.Rect(x, y, x+deltaBlock, y+deltaBlock).Intersect(b)
+			if !sameRegion(prev.base, base, t) {
+				copyCanvas(dst, base, t)
+				dirty = append(dirty, t)
+			}
+		}
+	}
+
+	var wm image.Image
+	for _, t := range dirty {
+		if !t.Overlaps(footprint) {
+			continue
+		}
+		if wm == nil {
+			wm = prepareWatermark(watermark, r.Rect.Size(), opts)
+		}
+		if opts.BackdropBlur > 0 {
+			// The blur mixes pixels across the footprint, so it is
+			// redone as a whole.
+			copyCanvas(dst, base, footprint)
+			composite(dst, wm, r, opts)
+			break
+		}
+		composite(subCanvas(dst, t), wm, r, opts)
+	}
+	rememberDelta(deltaResult{newSrc, watermark, key, base, cropCanvas(dst, b, gray)})
+	return dst
+}
+
+// lookupDelta returns the remembered result of watermarking src with
+// watermark and options hashing to optsHash, if there is one.
+func lookupDelta(src, watermark image.Image, optsHash string) (deltaResult, bool) {
+	deltaResults.mu.Lock()
+	defer deltaResults.mu.Unlock()
+	for i := len(deltaResults.results) - 1; i >= 0; i-- {
+		d := deltaResults.results[i]
+		if samePointer(d.src, src) && samePointer(d.watermark, watermark) && d.optsHash == optsHash {
+			return d, true
+		}
+	}
+	return deltaResult{}, false
+}
+
+// rememberDelta records d as the latest result for its source, forgetting
+// the oldest result once deltaHistory are held.
+func rememberDelta(d deltaResult) {
+	deltaResults.mu.Lock()
+	defer deltaResults.mu.Unlock()
+	results := make([]deltaResult, 0, deltaHistory)
+	for _, old := range deltaResults.results {
+		if !samePointer(old.src, d.src) || !samePointer(old.watermark, d.watermark) || old.optsHash != d.optsHash {
+			results = append(results, old)
+		}
+	}
+	if len(results) == deltaHistory {
+		results = results[1:]
+	}
+	deltaResults.results = append(results, d)
+}
+
+// samePointer reports whether a and b are the same image held by pointer.
+// Other image types are never reported as the same, since they may not be
+// comparable.
+func samePointer(a, b image.Image) bool {
+	t := reflect.TypeOf(a)
+	return t != nil && t.Kind() == reflect.Ptr && t == reflect.TypeOf(b) && a == b
+}
+
+// sameRegion reports whether a and b have identical pixels inside r. Pixels
+// are compared as stored, so images of different types never match.
+func sameRegion(a, b image.Image, r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if a.At(x, y) != b.At(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
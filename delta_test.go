@@ -0,0 +1,68 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestApplyDelta(t *testing.T) {
+	wm := photo(40, 30)
+	prevSrc := photo(160, 128)
+
+	// Edit one block away from the watermark and one under it.
+	newSrc := image.NewRGBA(prevSrc.Bounds())
+	draw.Draw(newSrc, newSrc.Bounds(), prevSrc, image.Point{}, draw.Src)
+	draw.Draw(newSrc, image.Rect(5, 5, 15, 15), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+	draw.Draw(newSrc, image.Rect(130, 100, 140, 110), image.NewUniform(color.RGBA{0, 255, 0, 255}), image.Point{}, draw.Src)
+
+	for _, opts := range []Options{
+		{Position: BottomRight, Opacity: 0.7, PaddingX: 5, PaddingY: 5},
+		{Position: BottomRight, Opacity: 0.7, PaddingX: 5, PaddingY: 5, BackdropBlur: 3},
+	} {
+		deltaResults.results = nil
+
+		// With nothing remembered for prevSrc, the watermark is applied in
+		// full.
+		if r := changed(ApplyDelta(prevSrc, prevSrc, wm, opts), Apply(prevSrc, wm, opts)); !r.Empty() {
+			t.Errorf("blur %d: first call differs from Apply in %v", opts.BackdropBlur, r)
+		}
+		if r := changed(ApplyDelta(prevSrc, newSrc, wm, opts), Apply(newSrc, wm, opts)); !r.Empty() {
+			t.Errorf("blur %d: differs from Apply in %v", opts.BackdropBlur, r)
+		}
+
+		// Mark a pixel of the remembered output in an unchanged block and
+		// one in a changed block: only the changed block is recomputed.
+		prev, ok := lookupDelta(prevSrc, wm, OptionsHash(opts))
+		if !ok {
+			t.Fatalf("blur %d: no result remembered for prevSrc", opts.BackdropBlur)
+		}
+		marked := prev.out.(*image.RGBA)
+		marked.SetRGBA(70, 70, color.RGBA{255, 0, 255, 255})
+		marked.SetRGBA(20, 20, color.RGBA{255, 0, 255, 255})
+		got := ApplyDelta(prevSrc, newSrc, wm, opts)
+		if c := rgbaAt(got, 70, 70); c != (color.RGBA{255, 0, 255, 255}) {
+			t.Errorf("blur %d: unchanged block pixel = %v, want it copied from the previous output", opts.BackdropBlur, c)
+		}
+		if c := rgbaAt(got, 20, 20); c != rgbaAt(newSrc, 20, 20) {
+			t.Errorf("blur %d: changed block pixel = %v, want it taken from the new source", opts.BackdropBlur, c)
+		}
+	}
+}
+
+func TestApplyDeltaInPlace(t *testing.T) {
+	deltaResults.results = nil
+	wm := photo(40, 30)
+	opts := Options{Position: BottomRight, Opacity: 0.7}
+
+	src := image.NewRGBA(image.Rect(0, 0, 160, 128))
+	draw.Draw(src, src.Bounds(), photo(160, 128), image.Point{}, draw.Src)
+	ApplyDelta(src, src, wm, opts)
+
+	// Edit the source in place, under the watermark.
+	draw.Draw(src, image.Rect(130, 100, 140, 110), image.NewUniform(color.RGBA{0, 255, 0, 255}), image.Point{}, draw.Src)
+	if r := changed(ApplyDelta(src, src, wm, opts), Apply(src, wm, opts)); !r.Empty() {
+		t.Errorf("differs from Apply in %v", r)
+	}
+}
@@ -0,0 +1,101 @@
+package watermark
+
+import (
+	"image"
+	"math"
+)
+
+// ApplyAvoidingDensity is like Apply but moves the watermark away from ink,
+// such as the text of a scanned document, so it does not hurt OCR. The
+// source is divided into blocks of blockSize pixels and the density of
+// dark, opaque pixels is measured in each. Among the placements aligned to
+// the block grid whose every block has a density at or below
+// densityThreshold (0-1), the one nearest where opts would place the
+// watermark is used; if there is none, the placement with the lowest mean
+// density wins. Position, padding, insets, offsets and Anchor only decide
+// that preferred spot.
+func ApplyAvoidingDensity(src, watermark image.Image, opts Options, blockSize int, densityThreshold float64) image.Image {
+	b := src.Bounds()
+	r := opts.Resolve(b, watermark.Bounds())
+	size := r.Rect.Size()
+	if blockSize <= 0 || size.X > b.Dx() || size.Y > b.Dy() {
+		return Apply(src, watermark, opts)
+	}
+
+	cols := (b.Dx() + blockSize - 1) / blockSize
+	rows := (b.Dy() + blockSize - 1) / blockSize
+	density := inkDensity(src, blockSize, cols, rows)
+
+	best := r.Rect.Min
+	bestClear, bestMean, bestDist := false, math.Inf(1), math.Inf(1)
+	for by := 0; by < rows; by++ {
+		for bx := 0; bx < cols; bx++ {
+			pt := image.Pt(
+				clampInt(b.Min.X+bx*blockSize, b.Min.X, b.Max.X-size.X),
+				clampInt(b.Min.Y+by*blockSize, b.Min.Y, b.Max.Y-size.Y),
+			)
+
+			// Blocks covered by the watermark at pt.
+			x0, x1 := (pt.X-b.Min.X)/blockSize, (pt.X-b.Min.X+size.X-1)/blockSize
+			y0, y1 := (pt.Y-b.Min.Y)/blockSize, (pt.Y-b.Min.Y+size.Y-1)/blockSize
+			max, sum := 0.0, 0.0
+			for y := y0; y <= y1; y++ {
+				for x := x0; x <= x1; x++ {
+					d := density[y*cols+x]
+					sum += d
+					max = math.Max(max, d)
+				}
+			}
+			clear := max <= densityThreshold
+			mean := sum / float64((x1-x0+1)*(y1-y0+1))
+			dist := math.Hypot(float64(pt.X-r.Rect.Min.X), float64(pt.Y-r.Rect.Min.Y))
+
+			better := false
+			switch {
+			case clear != bestClear:
+				better = clear
+			case clear:
+				better = dist < bestDist
+			default:
+				better = mean < bestMean || (mean == bestMean && dist < bestDist)
+			}
+			if better {
+				best, bestClear, bestMean, bestDist = pt, clear, mean, dist
+			}
+		}
+	}
+
+	// Anchor positions the slot the watermark is centered in, so find where
+	// an anchor at the origin puts the watermark and correct for it.
+	opts.OffsetX, opts.OffsetY = 0, 0
+	opts.Anchor = &image.Point{}
+	shift := opts.Resolve(b, watermark.Bounds()).Rect.Min.Sub(b.Min)
+	anchor := best.Sub(b.Min).Sub(shift)
+	opts.Anchor = &anchor
+	return Apply(src, watermark, opts)
+}
+
+// inkDensity returns, for each block of blockSize pixels in a cols by rows
+// grid over img, the fraction of its pixels that are dark and mostly
+// opaque.
+func inkDensity(img image.Image, blockSize, cols, rows int) []float64 {
+	b := img.Bounds()
+	ink := make([]int, cols*rows)
+	area := make([]int, cols*rows)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := (y-b.Min.Y)/blockSize*cols + (x-b.Min.X)/blockSize
+			area[i]++
+			cr, cg, cb, ca := img.At(x, y).RGBA()
+			if ca >= 0x8000 && luma(cr, cg, cb, ca) < 128 {
+				ink[i]++
+			}
+		}
+	}
+
+	density := make([]float64, cols*rows)
+	for i := range density {
+		density[i] = float64(ink[i]) / float64(area[i])
+	}
+	return density
+}
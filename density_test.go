@@ -0,0 +1,34 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyAvoidingDensity(t *testing.T) {
+	// A white page with lines of "text" down its left half.
+	doc := fill(200, 160, color.White)
+	for y := 0; y < 160; y += 4 {
+		for x := 0; x < 100; x++ {
+			if x%6 < 4 {
+				doc.SetRGBA(x, y, color.RGBA{0, 0, 0, 255})
+				doc.SetRGBA(x, y+1, color.RGBA{0, 0, 0, 255})
+			}
+		}
+	}
+	wm := fill(40, 20, color.RGBA{255, 0, 0, 255})
+	opts := Options{Position: TopLeft, Opacity: 1, PaddingX: 10, PaddingY: 10}
+	isRed := func(c color.RGBA) bool { return c.R > 200 && c.G < 50 }
+
+	if r := colorBounds(Apply(doc, wm, opts), isRed); !r.In(image.Rect(0, 0, 100, 160)) {
+		t.Fatalf("Apply placed the watermark at %v, want it over the text", r)
+	}
+	r := colorBounds(ApplyAvoidingDensity(doc, wm, opts, 16, 0.05), isRed)
+	if r.Dx() != 40 || r.Dy() != 20 {
+		t.Fatalf("watermark drawn at %v, want 40x20", r)
+	}
+	if !r.In(image.Rect(100, 0, 200, 160)) {
+		t.Errorf("watermark at %v, want it in the blank right half", r)
+	}
+}
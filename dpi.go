@@ -0,0 +1,106 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+)
+
+// SavePNGDPI is like SavePNG but declares a resolution of dpi dots per inch
+// in a pHYs chunk, for print workflows. A dpi of zero or less writes no
+// resolution, as SavePNG does.
+func SavePNGDPI(img image.Image, w io.Writer, dpi int) error {
+	if dpi <= 0 {
+		return SavePNG(img, w)
+	}
+	return observeEncode("png", 0, w, func(w io.Writer) error {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+		_, err := w.Write(pngWithDPI(buf.Bytes(), dpi))
+		return err
+	})
+}
+
+// SaveJPEGDPI is like SaveJPEG but declares a resolution of dpi dots per
+// inch in a JFIF header. A dpi of zero or less writes no resolution, as
+// SaveJPEG does.
+func SaveJPEGDPI(img image.Image, w io.Writer, quality, dpi int) error {
+	if dpi <= 0 {
+		return SaveJPEG(img, w, quality)
+	}
+	if quality <= 0 || quality > 100 {
+		quality = 85
+	}
+	return observeEncode("jpeg", quality, w, func(w io.Writer) error {
+		data, err := encodeJPEG(img, quality, dpi)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+}
+
+// encodeJPEG encodes img as JPEG at quality, declaring a resolution of dpi
+// if it is positive, without reporting to the encode hook.
+func encodeJPEG(img image.Image, quality, dpi int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	if dpi <= 0 {
+		return buf.Bytes(), nil
+	}
+	return jpegWithDPI(buf.Bytes(), dpi), nil
+}
+
+// pngWithDPI inserts a pHYs chunk declaring dpi after the IHDR chunk of
+// PNG data produced by png.Encode. PNG records pixels per metre.
+func pngWithDPI(data []byte, dpi int) []byte {
+	ppm := uint32(math.Round(float64(dpi) / 0.0254))
+	chunk := make([]byte, 4+4+9+4)
+	binary.BigEndian.PutUint32(chunk, 9)
+	copy(chunk[4:], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:], ppm)
+	binary.BigEndian.PutUint32(chunk[12:], ppm)
+	chunk[16] = 1 // unit: metre
+	binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+
+	// The signature is followed by IHDR: length, type, 13 bytes, CRC.
+	at := len(pngSignature) + 4 + 4 + 13 + 4
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:at]...)
+	out = append(out, chunk...)
+	return append(out, data[at:]...)
+}
+
+// jpegWithDPI inserts a JFIF APP0 segment declaring dpi straight after the
+// start-of-image marker of JPEG data produced by jpeg.Encode.
+func jpegWithDPI(data []byte, dpi int) []byte {
+	d := uint16(clampInt(dpi, 1, 0xffff))
+	seg := []byte{
+		0xff, 0xe0, 0, 16,
+		'J', 'F', 'I', 'F', 0,
+		1, 1, // version 1.01
+		1, // units: dots per inch
+		byte(d >> 8), byte(d), byte(d >> 8), byte(d),
+		0, 0, // no thumbnail
+	}
+	return jpegInsert(data, seg)
+}
+
+// jpegInsert returns JPEG data with the marker segment seg inserted straight
+// after the start-of-image marker, ahead of any segments already there.
+func jpegInsert(data, seg []byte) []byte {
+	out := make([]byte, 0, len(data)+len(seg))
+	out = append(out, data[:2]...)
+	out = append(out, seg...)
+	return append(out, data[2:]...)
+}
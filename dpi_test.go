@@ -0,0 +1,105 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pngDPI returns the resolution in a PNG's pHYs chunk in dots per inch, or
+// 0 if there is none.
+func pngDPI(data []byte) float64 {
+	for p := len(pngSignature); p+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[p:]))
+		if string(data[p+4:p+8]) == "pHYs" && n == 9 && data[p+16] == 1 {
+			return float64(binary.BigEndian.Uint32(data[p+8:])) * 0.0254
+		}
+		p += 12 + n
+	}
+	return 0
+}
+
+// jfifDPI returns the resolution in a JPEG's leading JFIF segment in dots
+// per inch, or 0 if it has none.
+func jfifDPI(data []byte) int {
+	if len(data) < 20 || data[2] != 0xff || data[3] != 0xe0 || string(data[6:11]) != "JFIF\x00" || data[13] != 1 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(data[14:]))
+}
+
+func TestSaveDPI(t *testing.T) {
+	img := photo(32, 24)
+	for _, dpi := range []int{72, 300, 600} {
+		var buf bytes.Buffer
+		if err := SavePNGDPI(img, &buf, dpi); err != nil {
+			t.Fatal(err)
+		}
+		if got := pngDPI(buf.Bytes()); math.Abs(got-float64(dpi)) > 0.05 {
+			t.Errorf("PNG at %d dpi declares %.2f", dpi, got)
+		}
+		if _, err := png.Decode(&buf); err != nil {
+			t.Errorf("PNG at %d dpi does not decode: %v", dpi, err)
+		}
+
+		buf.Reset()
+		if err := SaveJPEGDPI(img, &buf, 90, dpi); err != nil {
+			t.Fatal(err)
+		}
+		if got := jfifDPI(buf.Bytes()); got != dpi {
+			t.Errorf("JPEG at %d dpi declares %d", dpi, got)
+		}
+		if _, err := jpeg.Decode(&buf); err != nil {
+			t.Errorf("JPEG at %d dpi does not decode: %v", dpi, err)
+		}
+	}
+}
+
+func TestSaveDPIFile(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{OutputDPI: 300}
+	for _, name := range []string{"out.png", "out.jpg"} {
+		path := filepath.Join(dir, name)
+		if err := SaveWithSidecar(photo(32, 24), path, opts, "", 90); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := float64(jfifDPI(data))
+		if name == "out.png" {
+			got = pngDPI(data)
+		}
+		if math.Abs(got-300) > 0.05 {
+			t.Errorf("%s declares %.2f dpi, want 300", name, got)
+		}
+	}
+}
+
+func TestPreservingEXIFDPI(t *testing.T) {
+	src, mark := writeFiles(t, jpegWithEXIF(t, photo(64, 48), testEXIF(1, "Camera")))
+	out := filepath.Join(filepath.Dir(src), "out.jpg")
+	if err := WatermarkFilePreservingEXIF(src, mark, out, Options{OutputDPI: 300}, 90); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// JFIF leads, and the EXIF after it is intact.
+	if got := jfifDPI(data); got != 300 {
+		t.Errorf("declares %d dpi, want 300", got)
+	}
+	if got := exifASCII(jpegEXIF(data), 0x010f); got != "Camera" {
+		t.Errorf("camera make %q, want %q", got, "Camera")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("output does not decode: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package watermark
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/jpeg"
+	"io"
+)
+
+// SaveJPEGSSIM saves the watermarked image as JPEG using the lowest quality
+// whose decoded output still has an SSIM of at least minSSIM against img.
+// It returns the quality that was chosen. If no quality meets the threshold,
+// quality 100 is used. The final encode goes through SaveJPEG, so it is
+// reported to the encode hook.
+func SaveJPEGSSIM(img image.Image, w io.Writer, minSSIM float64) (int, error) {
+	quality := 100
+	lo, hi := 1, 100
+	for lo <= hi {
+		q := (lo + hi) / 2
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: q}); err != nil {
+			return 0, err
+		}
+		decoded, err := jpeg.Decode(&buf)
+		if err != nil {
+			return 0, err
+		}
+
+		if ssim(img, decoded) >= minSSIM {
+			quality = q
+			hi = q - 1
+		} else {
+			lo = q + 1
+		}
+	}
+
+	if err := SaveJPEG(img, w, quality); err != nil {
+		return 0, err
+	}
+	return quality, nil
+}
+
+// SaveJPEGHashed saves the watermarked image as JPEG and returns the SHA-256
+// digest of the bytes written, computed while encoding.
+func SaveJPEGHashed(img image.Image, w io.Writer, quality int) ([]byte, error) {
+	h := sha256.New()
+	if err := SaveJPEG(img, io.MultiWriter(w, h), quality); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// SaveJPEGPassthrough saves img as JPEG, unless it is pixel-for-pixel
+// identical to original, an encoded JPEG, in which case original is written
+// through unchanged to avoid generation loss from re-encoding. It reports
+// whether the original bytes were copied.
+func SaveJPEGPassthrough(img image.Image, original []byte, w io.Writer, quality int) (bool, error) {
+	prev, format, err := image.Decode(bytes.NewReader(original))
+	if err == nil && format == "jpeg" && samePixels(prev, img) {
+		if _, err := w.Write(original); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, SaveJPEG(img, w, quality)
+}
+
+// samePixels reports whether a and b have the same bounds and colors at 8
+// bits per channel. Decoded JPEGs report colors at 16 bits, which an 8-bit
+// copy of the same pixels would never match exactly.
+func samePixels(a, b image.Image) bool {
+	ab := a.Bounds()
+	if ab != b.Bounds() {
+		return false
+	}
+	for y := ab.Min.Y; y < ab.Max.Y; y++ {
+		for x := ab.Min.X; x < ab.Max.X; x++ {
+			ar, ag, abl, aa := a.At(x, y).RGBA()
+			br, bg, bbl, ba := b.At(x, y).RGBA()
+			if ar>>8 != br>>8 || ag>>8 != bg>>8 || abl>>8 != bbl>>8 || aa>>8 != ba>>8 {
+				return false
+			}
+		}
+	}
+	return true
+}
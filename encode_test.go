@@ -0,0 +1,96 @@
+package watermark
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/rand"
+	"testing"
+)
+
+// photo returns a w x h image with smooth gradients and mild noise, which
+// compresses like a photograph rather than flat artwork.
+func photo(w, h int) *image.RGBA {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			n := rng.Intn(16)
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8(x * 200 / w),
+				G: uint8(y * 200 / h),
+				B: uint8(100 + n),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+func TestSaveJPEGSSIM(t *testing.T) {
+	img := photo(128, 96)
+	const minSSIM = 0.95
+
+	var buf bytes.Buffer
+	quality, err := SaveJPEGSSIM(img, &buf, minSSIM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quality >= 100 {
+		t.Errorf("quality = %d, want below 100", quality)
+	}
+
+	decoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	if s := ssim(img, decoded); s < minSSIM {
+		t.Errorf("SSIM = %.4f, want at least %v", s, minSSIM)
+	}
+}
+
+func TestSaveJPEGHashed(t *testing.T) {
+	var buf bytes.Buffer
+	digest, err := SaveJPEGHashed(photo(32, 32), &buf, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256(buf.Bytes())
+	if !bytes.Equal(digest, want[:]) {
+		t.Errorf("digest = %x, want %x", digest, want)
+	}
+}
+
+func TestSaveJPEGPassthrough(t *testing.T) {
+	var orig bytes.Buffer
+	if err := jpeg.Encode(&orig, photo(48, 32), &jpeg.Options{Quality: 70}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(orig.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A fully transparent watermark changes no pixels.
+	noop := Apply(decoded, image.NewNRGBA(image.Rect(0, 0, 8, 8)), DefaultOptions())
+	var out bytes.Buffer
+	copied, err := SaveJPEGPassthrough(noop, orig.Bytes(), &out, 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !copied || !bytes.Equal(out.Bytes(), orig.Bytes()) {
+		t.Errorf("no-op transform: copied = %v, bytes equal = %v, want the original bytes", copied, bytes.Equal(out.Bytes(), orig.Bytes()))
+	}
+
+	// A visible watermark is re-encoded.
+	out.Reset()
+	marked := Apply(decoded, fill(8, 8, color.White), DefaultOptions())
+	if copied, err := SaveJPEGPassthrough(marked, orig.Bytes(), &out, 90); err != nil || copied {
+		t.Errorf("changed image: copied = %v, error = %v, want re-encoded", copied, err)
+	}
+	if _, err := jpeg.Decode(&out); err != nil {
+		t.Errorf("decoding re-encoded output: %v", err)
+	}
+}
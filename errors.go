@@ -0,0 +1,39 @@
+package watermark
+
+import "errors"
+
+// ErrWebPUnsupported is returned by the WebP helpers when the package was
+// built without the webp tag.
+var ErrWebPUnsupported = errors.New("watermark: WebP support requires building with -tags webp")
+
+// ErrImageTooSmall is returned when an image has too few pixels to carry an
+// invisible signature.
+var ErrImageTooSmall = errors.New("watermark: image too small to sign")
+
+// ErrUnsupportedFormat is returned when an output path has an extension the
+// package cannot encode.
+var ErrUnsupportedFormat = errors.New("watermark: unsupported output format")
+
+// ErrTooManyPixels is returned by SafeDecode when an image exceeds the
+// allowed pixel count.
+var ErrTooManyPixels = errors.New("watermark: image exceeds pixel limit")
+
+// ErrNoTransparency is returned by ValidateWatermark when a watermark has
+// too little transparency.
+var ErrNoTransparency = errors.New("watermark: watermark has no transparency")
+
+// ErrWatermarkTooLarge is returned by ValidateWatermark when a watermark
+// exceeds the allowed dimensions.
+var ErrWatermarkTooLarge = errors.New("watermark: watermark too large")
+
+// ErrDuplicateOutput is returned by ApplyFilesToDir when two source paths
+// share a file name, so their outputs would overwrite each other.
+var ErrDuplicateOutput = errors.New("watermark: sources share an output file name")
+
+// ErrOutputIsSource is returned by ApplyFilesToDir when outDir is the
+// directory of a source file, so its output would overwrite the source.
+var ErrOutputIsSource = errors.New("watermark: output directory holds a source file")
+
+// ErrNotAnimatedWebP is returned by ApplyAnimatedWebP when its input is not
+// a well-formed animated WebP.
+var ErrNotAnimatedWebP = errors.New("watermark: not an animated WebP")
@@ -0,0 +1,265 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// exifHeader prefixes the EXIF payload of a JPEG APP1 segment.
+const exifHeader = "Exif\x00\x00"
+
+// exifOrientationTag is the IFD0 tag holding the EXIF orientation.
+const exifOrientationTag = 0x0112
+
+// exifCopyrightTag is the IFD0 tag holding the copyright notice.
+const exifCopyrightTag = 0x8298
+
+// WatermarkFilePreservingEXIF watermarks the JPEG at srcPath and writes it
+// to outPath as a JPEG of the given quality, carrying over the source's
+// EXIF metadata. The source is first rotated and flipped upright as its
+// EXIF orientation asks, so the watermark lands where the viewer expects,
+// and the orientation written out is reset to 1 (upright). If
+// opts.Copyright is set it is written as the EXIF copyright notice,
+// replacing any the source had. Sources without EXIF are written without
+// it, or with EXIF holding only the notice. The watermark is loaded as
+// ApplyFromFiles loads it, and opts.OutputDPI is declared as SaveJPEGDPI
+// does.
+func WatermarkFilePreservingEXIF(srcPath, watermarkPath, outPath string, opts Options, quality int) error {
+	if ext := strings.ToLower(filepath.Ext(outPath)); ext != ".jpg" && ext != ".jpeg" {
+		return ErrUnsupportedFormat
+	}
+
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	wm, err := loadWatermark(watermarkPath, &opts)
+	if err != nil {
+		return err
+	}
+
+	exif := jpegEXIF(data)
+	if exif != nil {
+		exif = append([]byte(nil), exif...)
+		if orientation, at := exifOrientation(exif); at >= 0 {
+			src = orient(src, orientation)
+			exifByteOrder(exif).PutUint16(exif[at:], 1)
+		}
+	}
+	if opts.Copyright != "" {
+		exif = exifWithCopyright(exif, opts.Copyright)
+	}
+
+	if quality <= 0 || quality > 100 {
+		quality = 85
+	}
+	img := Apply(src, wm, opts)
+
+	// The encode hook sees the file as written, EXIF included.
+	var out bytes.Buffer
+	err = observeEncode("jpeg", quality, &out, func(w io.Writer) error {
+		jpg, err := encodeJPEG(img, quality, 0)
+		if err != nil {
+			return err
+		}
+		if n := len(exif) + 2; exif != nil && n <= 0xffff {
+			jpg = jpegInsert(jpg, append([]byte{0xff, 0xe1, byte(n >> 8), byte(n)}, exif...))
+		}
+		// The JFIF header must come first, so it is inserted last.
+		if opts.OutputDPI > 0 {
+			jpg = jpegWithDPI(jpg, opts.OutputDPI)
+		}
+		_, err = w.Write(jpg)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, out.Bytes(), 0o644)
+}
+
+// jpegEXIF returns the payload of the first EXIF APP1 segment in JPEG data,
+// starting with the "Exif\0\0" header, or nil if there is none.
+func jpegEXIF(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil
+	}
+	for p := 2; p+4 <= len(data) && data[p] == 0xff; {
+		marker := data[p+1]
+		if marker == 0xda || marker == 0xd9 {
+			// Start of scan or end of image: no more metadata segments.
+			return nil
+		}
+		n := int(binary.BigEndian.Uint16(data[p+2:]))
+		if n < 2 || p+2+n > len(data) {
+			return nil
+		}
+		body := data[p+4 : p+2+n]
+		if marker == 0xe1 && bytes.HasPrefix(body, []byte(exifHeader)) {
+			return body
+		}
+		p += 2 + n
+	}
+	return nil
+}
+
+// exifByteOrder returns the byte order of the TIFF structure in an EXIF
+// payload, which must be at least as long as its headers.
+func exifByteOrder(exif []byte) binary.ByteOrder {
+	if string(exif[len(exifHeader):len(exifHeader)+2]) == "MM" {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// exifIFD0 returns the offset of IFD0 within the TIFF structure of an EXIF
+// payload and its entry count, or -1 if the payload is malformed.
+func exifIFD0(exif []byte) (ifd, count int) {
+	if len(exif) < len(exifHeader)+8 {
+		return -1, 0
+	}
+	tiff := exif[len(exifHeader):]
+	if string(tiff[:2]) != "II" && string(tiff[:2]) != "MM" {
+		return -1, 0
+	}
+	order := exifByteOrder(exif)
+	ifd = int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return -1, 0
+	}
+	return ifd, int(order.Uint16(tiff[ifd:]))
+}
+
+// exifOrientation returns the orientation stored in IFD0 of an EXIF payload
+// and the offset of its value within exif, or -1 if there is none.
+func exifOrientation(exif []byte) (orientation int, at int) {
+	ifd, count := exifIFD0(exif)
+	if ifd < 0 {
+		return 0, -1
+	}
+	tiff := exif[len(exifHeader):]
+	order := exifByteOrder(exif)
+	for i := 0; i < count; i++ {
+		e := ifd + 2 + 12*i
+		if e+12 > len(tiff) {
+			break
+		}
+		// The tag must be a single SHORT, stored inline in the entry.
+		if order.Uint16(tiff[e:]) == exifOrientationTag && order.Uint16(tiff[e+2:]) == 3 && order.Uint32(tiff[e+4:]) == 1 {
+			return int(order.Uint16(tiff[e+8:])), len(exifHeader) + e + 8
+		}
+	}
+	return 0, -1
+}
+
+// exifWithCopyright returns a copy of an EXIF payload with its IFD0
+// copyright notice set to copyright, or a payload holding only the notice
+// if exif is nil. The new IFD0 is appended to the TIFF structure so no
+// other offset has to move; the old one is left unreferenced. Payloads
+// whose IFD0 cannot be read are returned unchanged.
+func exifWithCopyright(exif []byte, copyright string) []byte {
+	if exif == nil {
+		// A little-endian TIFF header and an empty IFD0.
+		exif = []byte(exifHeader + "II\x2a\x00\x08\x00\x00\x00" + "\x00\x00" + "\x00\x00\x00\x00")
+	}
+	ifd, count := exifIFD0(exif)
+	tiff := exif[len(exifHeader):]
+	if ifd < 0 || ifd+2+12*count+4 > len(tiff) {
+		return exif
+	}
+	order := exifByteOrder(exif)
+
+	// Keep every entry but an old notice, and add the new one in tag order.
+	var entries [][]byte
+	for i := 0; i < count; i++ {
+		e := tiff[ifd+2+12*i : ifd+2+12*(i+1)]
+		if order.Uint16(e) != exifCopyrightTag {
+			entries = append(entries, e)
+		}
+	}
+	value := append([]byte(copyright), 0)
+	notice := make([]byte, 12)
+	order.PutUint16(notice, exifCopyrightTag)
+	order.PutUint16(notice[2:], 2) // ASCII
+	order.PutUint32(notice[4:], uint32(len(value)))
+	entries = append(entries, notice)
+	sort.SliceStable(entries, func(i, j int) bool { return order.Uint16(entries[i]) < order.Uint16(entries[j]) })
+
+	out := append([]byte(exifHeader), tiff...)
+	if len(out)%2 == 1 {
+		// IFDs start on a word boundary.
+		out = append(out, 0)
+	}
+	at := len(out) - len(exifHeader)
+	end := at + 2 + 12*len(entries) + 4
+	if len(value) <= 4 {
+		copy(notice[8:], value)
+	} else {
+		order.PutUint32(notice[8:], uint32(end))
+	}
+
+	var n [4]byte
+	order.PutUint16(n[:], uint16(len(entries)))
+	out = append(out, n[:2]...)
+	for _, e := range entries {
+		out = append(out, e...)
+	}
+	out = append(out, tiff[ifd+2+12*count:ifd+2+12*count+4]...) // next IFD
+	if len(value) > 4 {
+		out = append(out, value...)
+	}
+	order.PutUint32(out[len(exifHeader)+4:], uint32(at))
+	return out
+}
+
+// orient returns img transformed to display upright for an EXIF
+// orientation: 2-4 mirror or rotate by 180 degrees and 5-8 additionally
+// swap width and height. Orientation 1 and unknown values return img
+// unchanged.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
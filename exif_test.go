@@ -0,0 +1,158 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testEXIF returns a little-endian EXIF payload whose IFD0 holds an
+// orientation and a camera make.
+func testEXIF(orientation int, camera string) []byte {
+	order := binary.LittleEndian
+	value := append([]byte(camera), 0)
+
+	tiff := []byte("II\x2a\x00\x08\x00\x00\x00")
+	entry := func(tag, typ uint16, count, v uint32) {
+		var e [12]byte
+		order.PutUint16(e[0:], tag)
+		order.PutUint16(e[2:], typ)
+		order.PutUint32(e[4:], count)
+		order.PutUint32(e[8:], v)
+		tiff = append(tiff, e[:]...)
+	}
+	tiff = append(tiff, 2, 0)
+	entry(0x010f, 2, uint32(len(value)), 8+2+2*12+4)
+	entry(exifOrientationTag, 3, 1, uint32(orientation))
+	tiff = append(tiff, 0, 0, 0, 0)
+	tiff = append(tiff, value...)
+	return append([]byte(exifHeader), tiff...)
+}
+
+// jpegWithEXIF encodes img as a JPEG carrying exif in an APP1 segment.
+func jpegWithEXIF(t testing.TB, img image.Image, exif []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	jpg := buf.Bytes()
+
+	var out bytes.Buffer
+	out.Write(jpg[:2])
+	n := len(exif) + 2
+	out.Write([]byte{0xff, 0xe1, byte(n >> 8), byte(n)})
+	out.Write(exif)
+	out.Write(jpg[2:])
+	return out.Bytes()
+}
+
+// exifASCII returns the ASCII value of tag in IFD0 of an EXIF payload, or
+// "" if there is none.
+func exifASCII(exif []byte, tag uint16) string {
+	ifd, count := exifIFD0(exif)
+	if ifd < 0 {
+		return ""
+	}
+	tiff := exif[len(exifHeader):]
+	order := exifByteOrder(exif)
+	for i := 0; i < count; i++ {
+		e := tiff[ifd+2+12*i:]
+		if order.Uint16(e) != tag || order.Uint16(e[2:]) != 2 {
+			continue
+		}
+		n := int(order.Uint32(e[4:]))
+		value := e[8:12]
+		if n > 4 {
+			at := int(order.Uint32(e[8:]))
+			value = tiff[at : at+n]
+		}
+		return string(bytes.TrimRight(value[:n], "\x00"))
+	}
+	return ""
+}
+
+// writeFiles writes a source JPEG and a small watermark PNG into a fresh
+// directory and returns their paths.
+func writeFiles(t *testing.T, jpg []byte) (src, mark string) {
+	t.Helper()
+	dir := t.TempDir()
+	src, mark = filepath.Join(dir, "src.jpg"), filepath.Join(dir, "mark.png")
+	if err := os.WriteFile(src, jpg, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mark, pngBytes(t, fill(8, 8, color.White)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return src, mark
+}
+
+func TestWatermarkFilePreservingEXIF(t *testing.T) {
+	// Orientation 6 stores the image rotated a quarter turn.
+	src, mark := writeFiles(t, jpegWithEXIF(t, photo(64, 48), testEXIF(6, "Camera")))
+	out := filepath.Join(filepath.Dir(src), "out.jpg")
+	opts := Options{Position: TopLeft, Opacity: 1, Copyright: "(c) 2026 Studio"}
+	if err := WatermarkFilePreservingEXIF(src, mark, out, opts, 90); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds().Size(); got != image.Pt(48, 64) {
+		t.Errorf("size %v, want the upright 48x64", got)
+	}
+	if c := rgbaAt(img, 3, 3); c.R < 240 || c.G < 240 || c.B < 240 {
+		t.Errorf("top-left pixel %v, want the watermark", c)
+	}
+
+	exif := jpegEXIF(data)
+	if exif == nil {
+		t.Fatal("output has no EXIF")
+	}
+	if orientation, _ := exifOrientation(exif); orientation != 1 {
+		t.Errorf("orientation %d, want 1", orientation)
+	}
+	if got := exifASCII(exif, 0x010f); got != "Camera" {
+		t.Errorf("camera make %q, want %q", got, "Camera")
+	}
+	if got := exifASCII(exif, exifCopyrightTag); got != opts.Copyright {
+		t.Errorf("copyright %q, want %q", got, opts.Copyright)
+	}
+}
+
+func TestEXIFWithCopyright(t *testing.T) {
+	tests := []struct {
+		name, copyright string
+		exif            []byte
+	}{
+		{"no EXIF", "(c) Studio", nil},
+		{"inline", "abc", testEXIF(1, "Camera")},
+		{"replaced", "(c) New", exifWithCopyright(testEXIF(1, "Camera"), "(c) Old")},
+	}
+	for _, tt := range tests {
+		exif := exifWithCopyright(tt.exif, tt.copyright)
+		if got := exifASCII(exif, exifCopyrightTag); got != tt.copyright {
+			t.Errorf("%s: copyright %q, want %q", tt.name, got, tt.copyright)
+		}
+		if tt.exif == nil {
+			continue
+		}
+		if got := exifASCII(exif, 0x010f); got != "Camera" {
+			t.Errorf("%s: camera make %q, want %q", tt.name, got, "Camera")
+		}
+		if _, count := exifIFD0(exif); count != 3 {
+			t.Errorf("%s: IFD0 has %d entries, want 3", tt.name, count)
+		}
+	}
+}
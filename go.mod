@@ -1,3 +1,9 @@
 module github.com/imgutils-org/imgutils-watermark
 
-go 1.16
+go 1.18
+
+require (
+	github.com/chai2010/webp v1.4.0
+	golang.org/x/image v0.0.0-20211028202545-6944b10bf410
+	golang.org/x/text v0.3.8
+)
@@ -0,0 +1,88 @@
+package watermark
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+	"math"
+	"reflect"
+)
+
+// OptionsHash returns a stable hex-encoded SHA-256 of opts, suitable as a
+// cache key component. Equal options always hash the same, and any
+// options, including ones holding infinities or NaN, can be hashed.
+func OptionsHash(opts Options) string {
+	h := sha256.New()
+	hashValue(h, reflect.ValueOf(opts))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashValue writes a canonical binary encoding of v to h: struct fields by
+// name and value, integers and booleans as 64-bit values, strings by length
+// and content, floats by their IEEE 754 bits with negative zero and NaNs
+// normalized, and pointers as a presence flag followed by what they point
+// to.
+func hashValue(h hash.Hash, v reflect.Value) {
+	var buf [8]byte
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			io.WriteString(h, t.Field(i).Name)
+			hashValue(h, v.Field(i))
+		}
+		return
+	case reflect.Ptr:
+		if v.IsNil() {
+			h.Write([]byte{0})
+			return
+		}
+		h.Write([]byte{1})
+		hashValue(h, v.Elem())
+		return
+	case reflect.Bool:
+		if v.Bool() {
+			buf[7] = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		binary.BigEndian.PutUint64(buf[:], uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		binary.BigEndian.PutUint64(buf[:], v.Uint())
+	case reflect.String:
+		binary.BigEndian.PutUint64(buf[:], uint64(v.Len()))
+		h.Write(buf[:])
+		io.WriteString(h, v.String())
+		return
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		switch {
+		case f == 0:
+			f = 0
+		case math.IsNaN(f):
+			f = math.NaN()
+		}
+		binary.BigEndian.PutUint64(buf[:], math.Float64bits(f))
+	default:
+		// Options is made of the kinds above; a new field of another kind
+		// needs encoding here.
+		panic("watermark: cannot hash a field of type " + v.Type().String())
+	}
+	h.Write(buf[:])
+}
+
+// TransformKey combines the source content hash, a hash of the watermark
+// bytes and the options hash into the canonical cache key for a full
+// transform. Changing any of the three changes the key.
+func TransformKey(srcHash string, wmBytes []byte, opts Options) string {
+	wmSum := sha256.Sum256(wmBytes)
+
+	h := sha256.New()
+	h.Write([]byte(srcHash))
+	h.Write([]byte{0})
+	h.Write([]byte(hex.EncodeToString(wmSum[:])))
+	h.Write([]byte{0})
+	h.Write([]byte(OptionsHash(opts)))
+	return hex.EncodeToString(h.Sum(nil))
+}
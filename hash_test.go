@@ -0,0 +1,75 @@
+package watermark
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestOptionsHash(t *testing.T) {
+	base := DefaultOptions()
+	if OptionsHash(base) != OptionsHash(DefaultOptions()) {
+		t.Error("equal options hash differently")
+	}
+
+	anchor := image.Pt(0, 0)
+	variants := []Options{
+		{Position: BottomRight, Opacity: 0.5, PaddingX: 10, PaddingY: 11},
+		{Position: BottomRight, Opacity: 0.6, PaddingX: 10, PaddingY: 10},
+		{Position: BottomRight, Opacity: 0.5, PaddingX: 10, PaddingY: 10, RTL: true},
+		{Position: BottomRight, Opacity: 0.5, PaddingX: 10, PaddingY: 10, Anchor: &anchor},
+		{Position: BottomRight, Opacity: 0.5, PaddingX: 10, PaddingY: 10, Insets: Insets{Left: 1}},
+		{Position: BottomRight, Opacity: 0.5, PaddingX: 10, PaddingY: 10, Copyright: "x"},
+	}
+	seen := map[string]int{OptionsHash(base): -1}
+	for i, o := range variants {
+		h := OptionsHash(o)
+		if j, ok := seen[h]; ok {
+			t.Errorf("variant %d hashes the same as %d", i, j)
+		}
+		seen[h] = i
+	}
+
+	// The anchor is hashed by value, not by address.
+	other := image.Pt(0, 0)
+	a, b := base, base
+	a.Anchor, b.Anchor = &anchor, &other
+	if OptionsHash(a) != OptionsHash(b) {
+		t.Error("equal anchors at different addresses hash differently")
+	}
+}
+
+func TestOptionsHashNonFinite(t *testing.T) {
+	for _, v := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+		opts := Options{Position: Polar, Bearing: v, Opacity: v}
+		if OptionsHash(opts) != OptionsHash(opts) {
+			t.Errorf("Bearing %v: hash is not stable", v)
+		}
+	}
+	if OptionsHash(Options{Bearing: math.Copysign(0, -1)}) != OptionsHash(Options{}) {
+		t.Error("negative zero hashes differently from zero")
+	}
+	if OptionsHash(Options{Bearing: math.Inf(1)}) == OptionsHash(Options{Bearing: math.Inf(-1)}) {
+		t.Error("+Inf and -Inf hash the same")
+	}
+}
+
+func TestTransformKey(t *testing.T) {
+	opts := DefaultOptions()
+	key := TransformKey("src", []byte("wm"), opts)
+	if key != TransformKey("src", []byte("wm"), DefaultOptions()) {
+		t.Error("key is not stable")
+	}
+
+	changedOpts := opts
+	changedOpts.Opacity = 0.7
+	for name, k := range map[string]string{
+		"source":    TransformKey("src2", []byte("wm"), opts),
+		"watermark": TransformKey("src", []byte("wm2"), opts),
+		"options":   TransformKey("src", []byte("wm"), changedOpts),
+	} {
+		if k == key {
+			t.Errorf("changing the %s did not change the key", name)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package watermark
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// EncodeEvent describes a completed encode by one of the save helpers.
+type EncodeEvent struct {
+	// Format is the encoder used: "jpeg", "png" or "webp".
+	Format string
+	// Quality is the quality setting passed to the encoder, or 0 for
+	// lossless formats.
+	Quality  int
+	Bytes    int64
+	Duration time.Duration
+	Err      error
+}
+
+var (
+	encodeHookMu sync.RWMutex
+	encodeHook   func(EncodeEvent)
+)
+
+// SetEncodeHook installs fn to be called after every encode performed by
+// SaveJPEG, SavePNG and the WebP helpers, for logging or metrics. Passing
+// nil removes the hook. fn may be called from multiple goroutines.
+func SetEncodeHook(fn func(EncodeEvent)) {
+	encodeHookMu.Lock()
+	encodeHook = fn
+	encodeHookMu.Unlock()
+}
+
+// observeEncode runs encode against w and reports the outcome to the
+// encode hook, if one is installed.
+func observeEncode(format string, quality int, w io.Writer, encode func(io.Writer) error) error {
+	encodeHookMu.RLock()
+	hook := encodeHook
+	encodeHookMu.RUnlock()
+
+	if hook == nil {
+		return encode(w)
+	}
+
+	cw := &countingWriter{w: w}
+	start := time.Now()
+	err := encode(cw)
+	hook(EncodeEvent{
+		Format:   format,
+		Quality:  quality,
+		Bytes:    cw.n,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+	return err
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,91 @@
+package watermark
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeHook(t *testing.T) {
+	var events []EncodeEvent
+	SetEncodeHook(func(e EncodeEvent) { events = append(events, e) })
+	defer SetEncodeHook(nil)
+
+	img := photo(64, 48)
+	tests := []struct {
+		name    string
+		save    func(*bytes.Buffer) error
+		format  string
+		quality int
+	}{
+		{"SaveJPEG", func(w *bytes.Buffer) error { return SaveJPEG(img, w, 70) }, "jpeg", 70},
+		{"SaveJPEG default", func(w *bytes.Buffer) error { return SaveJPEG(img, w, 0) }, "jpeg", 85},
+		{"SavePNG", func(w *bytes.Buffer) error { return SavePNG(img, w) }, "png", 0},
+		{"SaveJPEGDPI", func(w *bytes.Buffer) error { return SaveJPEGDPI(img, w, 80, 300) }, "jpeg", 80},
+		{"SavePNGDPI", func(w *bytes.Buffer) error { return SavePNGDPI(img, w, 300) }, "png", 0},
+	}
+	for _, tt := range tests {
+		events = nil
+		var buf bytes.Buffer
+		if err := tt.save(&buf); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("%s: %d events, want 1", tt.name, len(events))
+		}
+		e := events[0]
+		if e.Format != tt.format || e.Quality != tt.quality {
+			t.Errorf("%s: event %s q%d, want %s q%d", tt.name, e.Format, e.Quality, tt.format, tt.quality)
+		}
+		if e.Bytes != int64(buf.Len()) {
+			t.Errorf("%s: event reports %d bytes, wrote %d", tt.name, e.Bytes, buf.Len())
+		}
+		if e.Err != nil {
+			t.Errorf("%s: event error %v", tt.name, e.Err)
+		}
+	}
+}
+
+func TestEncodeHookSSIM(t *testing.T) {
+	var events []EncodeEvent
+	SetEncodeHook(func(e EncodeEvent) { events = append(events, e) })
+	defer SetEncodeHook(nil)
+
+	var buf bytes.Buffer
+	quality, err := SaveJPEGSSIM(photo(64, 48), &buf, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the final encode is reported, not the search.
+	if len(events) != 1 {
+		t.Fatalf("%d events, want 1", len(events))
+	}
+	if e := events[0]; e.Format != "jpeg" || e.Quality != quality || e.Bytes != int64(buf.Len()) {
+		t.Errorf("event %s q%d %d bytes, want jpeg q%d %d bytes", e.Format, e.Quality, e.Bytes, quality, buf.Len())
+	}
+}
+
+func TestEncodeHookEXIF(t *testing.T) {
+	src, mark := writeFiles(t, jpegWithEXIF(t, photo(64, 48), testEXIF(1, "Camera")))
+
+	var events []EncodeEvent
+	SetEncodeHook(func(e EncodeEvent) { events = append(events, e) })
+	defer SetEncodeHook(nil)
+
+	out := filepath.Join(filepath.Dir(src), "out.jpg")
+	if err := WatermarkFilePreservingEXIF(src, mark, out, Options{Opacity: 1}, 90); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("%d events, want 1", len(events))
+	}
+	// The reported size includes the EXIF segment.
+	if e := events[0]; e.Format != "jpeg" || e.Quality != 90 || e.Bytes != info.Size() {
+		t.Errorf("event %s q%d %d bytes, want jpeg q90 %d bytes", e.Format, e.Quality, e.Bytes, info.Size())
+	}
+}
@@ -0,0 +1,57 @@
+package watermark
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// PerceptualHash computes a 64-bit DCT-based perceptual hash (pHash) of img.
+// Visually similar images, including lightly edited or re-encoded copies,
+// have hashes a small Hamming distance apart, which makes it useful for
+// duplicate detection.
+func PerceptualHash(img image.Image) uint64 {
+	const size, low = 32, 8
+
+	small := resize(img, size, size)
+	var pixels [size][size]float64
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			pixels[y][x] = luma(small.At(x, y).RGBA())
+		}
+	}
+
+	// Only the low-frequency corner of the 2D DCT-II is needed.
+	var cos [low][size]float64
+	for u := 0; u < low; u++ {
+		for x := 0; x < size; x++ {
+			cos[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * size))
+		}
+	}
+	var coeffs [low * low]float64
+	for v := 0; v < low; v++ {
+		for u := 0; u < low; u++ {
+			var sum float64
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					sum += pixels[y][x] * cos[u][x] * cos[v][y]
+				}
+			}
+			coeffs[v*low+u] = sum
+		}
+	}
+
+	// The DC term reflects only overall brightness, so leave it out of the
+	// median.
+	sorted := append([]float64(nil), coeffs[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for i, c := range coeffs {
+		if c > median {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
@@ -0,0 +1,54 @@
+package watermark
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// blocks returns a w x h image of random gray blocks, a scene with detail
+// at the scales pHash looks at.
+func blocks(w, h int, seed int64) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	var shades [6][6]uint8
+	for i := range shades {
+		for j := range shades[i] {
+			shades[i][j] = uint8(rng.Intn(256))
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := shades[y*6/h][x*6/w]
+			img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestPerceptualHash(t *testing.T) {
+	img := blocks(160, 120, 1)
+	hash := PerceptualHash(img)
+
+	// A lightly watermarked and re-encoded copy.
+	var buf bytes.Buffer
+	marked := Apply(img, fill(20, 10, color.White), Options{Position: BottomRight, Opacity: 0.3})
+	if err := jpeg.Encode(&buf, marked, &jpeg.Options{Quality: 70}); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := bits.OnesCount64(hash ^ PerceptualHash(copied)); d > 4 {
+		t.Errorf("edited copy: distance %d, want at most 4", d)
+	}
+
+	if d := bits.OnesCount64(hash ^ PerceptualHash(blocks(160, 120, 2))); d < 16 {
+		t.Errorf("different image: distance %d, want at least 16", d)
+	}
+}
@@ -0,0 +1,67 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// pngSignature is the fixed header every PNG file starts with.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// pngOffsetKey is the tEXt keyword our tooling uses to embed a suggested
+// watermark offset.
+const pngOffsetKey = "wm:offset"
+
+// pngOffset looks for a "wm:offset" tEXt chunk of the form "x,y" in PNG data
+// and returns the offset it holds.
+func pngOffset(data []byte) (image.Point, bool) {
+	text, ok := pngText(data, pngOffsetKey)
+	if !ok {
+		return image.Point{}, false
+	}
+
+	parts := strings.Split(text, ",")
+	if len(parts) != 2 {
+		return image.Point{}, false
+	}
+	x, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return image.Point{}, false
+	}
+	y, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return image.Point{}, false
+	}
+	return image.Pt(x, y), true
+}
+
+// pngText returns the value of the first tEXt chunk in PNG data whose
+// keyword is key.
+func pngText(data []byte, key string) (string, bool) {
+	if !bytes.HasPrefix(data, []byte(pngSignature)) {
+		return "", false
+	}
+
+	for p := len(pngSignature); p+8 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[p:]))
+		typ := string(data[p+4 : p+8])
+		if n < 0 || p+12+n > len(data) {
+			return "", false
+		}
+		body := data[p+8 : p+8+n]
+
+		switch typ {
+		case "tEXt":
+			if i := bytes.IndexByte(body, 0); i >= 0 && string(body[:i]) == key {
+				return string(body[i+1:]), true
+			}
+		case "IEND":
+			return "", false
+		}
+		p += 12 + n
+	}
+	return "", false
+}
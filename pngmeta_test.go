@@ -0,0 +1,90 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withText returns PNG data with a tEXt chunk holding key and value
+// inserted after the IHDR chunk.
+func withText(data []byte, key, value string) []byte {
+	body := append(append([]byte(key), 0), value...)
+	chunk := make([]byte, 12+len(body))
+	binary.BigEndian.PutUint32(chunk, uint32(len(body)))
+	copy(chunk[4:], "tEXt")
+	copy(chunk[8:], body)
+	binary.BigEndian.PutUint32(chunk[8+len(body):], crc32.ChecksumIEEE(chunk[4:8+len(body)]))
+
+	at := len(pngSignature) + 4 + 4 + 13 + 4
+	return append(append(append([]byte(nil), data[:at]...), chunk...), data[at:]...)
+}
+
+func TestApplyFromFilesPNGOffset(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.png")
+	wmPath := filepath.Join(dir, "wm.png")
+
+	var buf bytes.Buffer
+	if err := SavePNG(fill(100, 80, color.Black), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(srcPath, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := SavePNG(fill(10, 10, color.White), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(wmPath, withText(buf.Bytes(), "wm:offset", "30,40"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ApplyFromFiles(srcPath, wmPath, Options{Position: BottomRight, Opacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := changed(fill(100, 80, color.Black), out); r != image.Rect(30, 40, 40, 50) {
+		t.Errorf("watermark at %v, want at the embedded offset (30, 40)", r)
+	}
+
+	// An explicit anchor wins over the embedded offset.
+	out, err = ApplyFromFiles(srcPath, wmPath, Options{Opacity: 1, Anchor: &image.Point{5, 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := changed(fill(100, 80, color.Black), out); r != image.Rect(5, 5, 15, 15) {
+		t.Errorf("anchored watermark at %v, want at (5, 5)", r)
+	}
+}
+
+func TestPNGOffset(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SavePNG(fill(2, 2, color.White), &buf); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value string
+		want  image.Point
+		ok    bool
+	}{
+		{"12,34", image.Pt(12, 34), true},
+		{" -3 , 7 ", image.Pt(-3, 7), true},
+		{"12", image.Point{}, false},
+		{"a,b", image.Point{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := pngOffset(withText(buf.Bytes(), pngOffsetKey, tt.value))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("pngOffset(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+	if _, ok := pngOffset(buf.Bytes()); ok {
+		t.Error("pngOffset found an offset in a PNG without one")
+	}
+}
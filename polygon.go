@@ -0,0 +1,62 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// ApplyPolygon applies a watermark like Apply, but only inside polygon.
+// Polygon vertices are in source coordinates and the interior is decided by
+// the even-odd rule, so concave and self-intersecting shapes are supported.
+func ApplyPolygon(src, watermark image.Image, polygon []image.Point, opts Options) image.Image {
+	b := src.Bounds()
+	marked := Apply(src, watermark, opts)
+
+	mask := polygonMask(b, polygon)
+
+	dst := newCanvas(src)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if mask.AlphaAt(x, y).A != 0 {
+				dst.Set(x, y, marked.At(x, y))
+			}
+		}
+	}
+	return dst
+}
+
+// polygonMask rasterizes polygon into an alpha mask covering bounds. A pixel
+// is inside when its center is, according to the even-odd rule.
+func polygonMask(bounds image.Rectangle, polygon []image.Point) *image.Alpha {
+	mask := image.NewAlpha(bounds)
+	if len(polygon) < 3 {
+		return mask
+	}
+
+	var xs []float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		cy := float64(y) + 0.5
+
+		xs = xs[:0]
+		for i, p := range polygon {
+			q := polygon[(i+1)%len(polygon)]
+			py, qy := float64(p.Y), float64(q.Y)
+			if (py <= cy) == (qy <= cy) {
+				continue
+			}
+			t := (cy - py) / (qy - py)
+			xs = append(xs, float64(p.X)+t*float64(q.X-p.X))
+		}
+		sort.Float64s(xs)
+
+		for i := 0; i+1 < len(xs); i += 2 {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if cx := float64(x) + 0.5; cx >= xs[i] && cx < xs[i+1] {
+					mask.SetAlpha(x, y, color.Alpha{A: 0xff})
+				}
+			}
+		}
+	}
+	return mask
+}
@@ -0,0 +1,44 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyPolygonTriangle(t *testing.T) {
+	src := fill(100, 100, color.Black)
+	wm := fill(100, 100, color.White)
+	triangle := []image.Point{{10, 10}, {90, 10}, {10, 90}}
+	out := ApplyPolygon(src, wm, triangle, Options{Position: Center, Opacity: 1})
+
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			// A pixel is inside when its center is.
+			cx, cy := float64(x)+0.5, float64(y)+0.5
+			inside := cx >= 10 && cy >= 10 && cx+cy < 100
+			if got := rgbaAt(out, x, y).R == 255; got != inside {
+				t.Fatalf("pixel (%d, %d) changed = %v, want %v", x, y, got, inside)
+			}
+		}
+	}
+}
+
+func TestPolygonMaskEvenOdd(t *testing.T) {
+	// A concave "U": the notch between its arms is outside.
+	u := []image.Point{{0, 0}, {10, 0}, {10, 30}, {20, 30}, {20, 0}, {30, 0}, {30, 40}, {0, 40}}
+	mask := polygonMask(image.Rect(0, 0, 30, 40), u)
+	for _, tt := range []struct {
+		p      image.Point
+		inside bool
+	}{
+		{image.Pt(5, 5), true},
+		{image.Pt(25, 5), true},
+		{image.Pt(15, 5), false},
+		{image.Pt(15, 35), true},
+	} {
+		if got := mask.AlphaAt(tt.p.X, tt.p.Y).A != 0; got != tt.inside {
+			t.Errorf("pixel %v inside = %v, want %v", tt.p, got, tt.inside)
+		}
+	}
+}
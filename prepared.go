@@ -0,0 +1,46 @@
+package watermark
+
+import "image"
+
+// PreparedWatermark is a watermark resolved, scaled and otherwise prepared
+// for sources of one size, so the expensive preparation can be done once,
+// ahead of time, and shared by any number of ApplyTo calls. It is safe for
+// concurrent use.
+type PreparedWatermark struct {
+	watermark image.Image
+	prepared  image.Image
+	resolved  ResolvedOptions
+	opts      Options
+	bounds    image.Rectangle
+}
+
+// PrepareWatermark prepares watermark for sources with targetBounds as
+// opts would place it, including scaling, alpha thresholding and
+// feathering. AutoComplementTint depends on each source and is still done
+// per image.
+func PrepareWatermark(watermark image.Image, opts Options, targetBounds image.Rectangle) PreparedWatermark {
+	p := PreparedWatermark{watermark: watermark, opts: opts, bounds: targetBounds}
+	if !opts.AutoComplementTint {
+		p.resolved = opts.Resolve(targetBounds, watermark.Bounds())
+		p.prepared = prepareWatermark(watermark, p.resolved.Rect.Size(), opts)
+	}
+	return p
+}
+
+// ApplyTo returns a watermarked copy of src, as Apply would. Sources whose
+// bounds differ from the target bounds the watermark was prepared for are
+// handled by Apply.
+func (p PreparedWatermark) ApplyTo(src image.Image) image.Image {
+	if p.prepared == nil || src.Bounds() != p.bounds {
+		return Apply(src, p.watermark, p.opts)
+	}
+	return applyPrepared(src, p.watermark, p.prepared, p.resolved, p.opts)
+}
+
+// applyPrepared is Apply with watermark already resolved as r and prepared
+// as prepared.
+func applyPrepared(src, watermark, prepared image.Image, r ResolvedOptions, opts Options) image.Image {
+	dst := cropCanvas(src, src.Bounds(), keepsGray(src, watermark, opts))
+	composite(dst, prepared, r, opts)
+	return dst
+}
@@ -0,0 +1,51 @@
+package watermark
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPreparedWatermark(t *testing.T) {
+	wm := photo(300, 150)
+	opts := Options{Position: BottomRight, Opacity: 0.7, Scale: 0.3, PaddingX: 6, PaddingY: 6, Feather: 2}
+	target := image.Rect(0, 0, 320, 240)
+	p := PrepareWatermark(wm, opts, target)
+
+	for _, src := range []image.Image{
+		photo(320, 240),
+		photo(200, 100), // a size it was not prepared for
+	} {
+		got, want := p.ApplyTo(src), Apply(src, wm, opts)
+		if got.Bounds() != want.Bounds() {
+			t.Errorf("source %v: bounds %v, want %v", src.Bounds(), got.Bounds(), want.Bounds())
+			continue
+		}
+		if r := changed(got, want); !r.Empty() {
+			t.Errorf("source %v: differs from Apply in %v", src.Bounds(), r)
+		}
+	}
+}
+
+func BenchmarkPreparedWatermark(b *testing.B) {
+	wm := photo(1200, 600)
+	opts := Options{Position: BottomRight, Opacity: 0.7, Scale: 0.25}
+	src := photo(800, 600)
+
+	b.Run("prepare", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			PrepareWatermark(wm, opts, src.Bounds())
+		}
+	})
+	b.Run("ApplyTo", func(b *testing.B) {
+		p := PrepareWatermark(wm, opts, src.Bounds())
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p.ApplyTo(src)
+		}
+	})
+	b.Run("Apply", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Apply(src, wm, opts)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package watermark
+
+import "image"
+
+// presetPositions lists the corner, center and golden-ratio preset
+// positions.
+var presetPositions = []Position{
+	Center, TopLeft, TopRight, BottomLeft, BottomRight,
+	GoldenTopLeft, GoldenTopRight, GoldenBottomLeft, GoldenBottomRight,
+}
+
+// PreviewPositions stamps the watermark in each corner, the center and each
+// golden-ratio point of a single copy of src, so users can compare
+// placements side by side. The Position field of opts is ignored; all other
+// options apply to each stamp.
+func PreviewPositions(src, watermark image.Image, opts Options) image.Image {
+	dst := newCanvas(src)
+	for _, pos := range presetPositions {
+		opts.Position = pos
+		applyTo(dst, watermark, opts)
+	}
+	return dst
+}
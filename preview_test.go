@@ -0,0 +1,32 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPreviewPositions(t *testing.T) {
+	src := fill(200, 120, color.Black)
+	out := PreviewPositions(src, fill(20, 20, color.White), Options{Opacity: 1, PaddingX: 5, PaddingY: 5})
+
+	stamps := map[string]image.Point{
+		"top-left":            {10, 10},
+		"top-right":           {190, 10},
+		"bottom-left":         {10, 110},
+		"bottom-right":        {190, 110},
+		"center":              {100, 60},
+		"golden top-left":     {76, 46},
+		"golden top-right":    {124, 46},
+		"golden bottom-left":  {76, 74},
+		"golden bottom-right": {124, 74},
+	}
+	for name, p := range stamps {
+		if c := rgbaAt(out, p.X, p.Y); c.R != 255 {
+			t.Errorf("%s stamp at %v = %v, want white", name, p, c)
+		}
+	}
+	if c := rgbaAt(out, 50, 60); c.R != 0 {
+		t.Errorf("unstamped pixel = %v, want black", c)
+	}
+}
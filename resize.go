@@ -0,0 +1,79 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// resize scales img to w x h using bilinear interpolation. Sampling is done
+// on the premultiplied values returned by At, and the result is kept
+// premultiplied, so transparent pixels do not bleed their color into
+// neighbouring opaque ones and edges do not darken into a halo.
+func resize(img image.Image, w, h int) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if w <= 0 || h <= 0 || b.Empty() {
+		return dst
+	}
+
+	sx := float64(b.Dx()) / float64(w)
+	sy := float64(b.Dy()) / float64(h)
+
+	for y := 0; y < h; y++ {
+		fy := (float64(y)+0.5)*sy - 0.5
+		y0, y1, ty := sampleSpan(fy, b.Dy())
+		for x := 0; x < w; x++ {
+			fx := (float64(x)+0.5)*sx - 0.5
+			x0, x1, tx := sampleSpan(fx, b.Dx())
+
+			r00, g00, b00, a00 := img.At(b.Min.X+x0, b.Min.Y+y0).RGBA()
+			r10, g10, b10, a10 := img.At(b.Min.X+x1, b.Min.Y+y0).RGBA()
+			r01, g01, b01, a01 := img.At(b.Min.X+x0, b.Min.Y+y1).RGBA()
+			r11, g11, b11, a11 := img.At(b.Min.X+x1, b.Min.Y+y1).RGBA()
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: lerp2(r00, r10, r01, r11, tx, ty),
+				G: lerp2(g00, g10, g01, g11, tx, ty),
+				B: lerp2(b00, b10, b01, b11, tx, ty),
+				A: lerp2(a00, a10, a01, a11, tx, ty),
+			})
+		}
+	}
+	return dst
+}
+
+// sampleSpan returns the two source indices surrounding f and the weight of
+// the second, clamped to [0, n).
+func sampleSpan(f float64, n int) (int, int, float64) {
+	if f < 0 {
+		f = 0
+	}
+	i0 := int(f)
+	if i0 >= n-1 {
+		return n - 1, n - 1, 0
+	}
+	return i0, i0 + 1, f - float64(i0)
+}
+
+// lerp2 bilinearly interpolates four 16-bit samples down to 8 bits,
+// rounding to nearest so colors are not biased darker.
+func lerp2(v00, v10, v01, v11 uint32, tx, ty float64) uint8 {
+	top := float64(v00)*(1-tx) + float64(v10)*tx
+	bottom := float64(v01)*(1-tx) + float64(v11)*tx
+	return uint8(math.Round((top*(1-ty) + bottom*ty) / 257))
+}
+
+// Resize scales img to width x height. When downscaling, a positive sharpen
+// applies an unsharp mask of that strength afterwards to counter the
+// softness resampling introduces; around 0.5 is a mild setting. Sharpening
+// is skipped when neither dimension shrinks, as it would only exaggerate
+// the interpolation.
+func Resize(img image.Image, width, height int, sharpen float64) *image.RGBA {
+	b := img.Bounds()
+	dst := resize(img, width, height)
+	if sharpen > 0 && (width < b.Dx() || height < b.Dy()) {
+		unsharpMask(dst, 1, sharpen)
+	}
+	return dst
+}
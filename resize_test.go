@@ -0,0 +1,106 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// stripes returns a w x h image of vertical black and white stripes, each
+// period pixels wide.
+func stripes(w, h, period int) *image.RGBA {
+	img := fill(w, h, color.Black)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x/period%2 == 1 {
+				img.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+// edgeContrast returns the range of red values across the middle row of
+// img. Sharpening overshoots on both sides of an edge, widening it.
+func edgeContrast(img image.Image) int {
+	b := img.Bounds()
+	y := b.Min.Y + b.Dy()/2
+	lo, hi := 255, 0
+	for x := b.Min.X; x < b.Max.X; x++ {
+		v := int(rgbaAt(img, x, y).R)
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	return hi - lo
+}
+
+func TestResizeSharpen(t *testing.T) {
+	// A mid-gray step leaves room for overshoot on both sides.
+	src := fill(121, 40, color.RGBA{64, 64, 64, 255})
+	draw.Draw(src, image.Rect(61, 0, 121, 40), image.NewUniform(color.RGBA{192, 192, 192, 255}), image.Point{}, draw.Src)
+
+	soft := Resize(src, 40, 13, 0)
+	sharp := Resize(src, 40, 13, 0.8)
+	if s, h := edgeContrast(soft), edgeContrast(sharp); h <= s {
+		t.Errorf("edge contrast with sharpening = %d, want above %d", h, s)
+	}
+
+	// Upscaling is never sharpened.
+	small := stripes(40, 10, 4)
+	if r := changed(Resize(small, 80, 20, 0), Resize(small, 80, 20, 0.8)); !r.Empty() {
+		t.Errorf("sharpening changed an upscale in %v", r)
+	}
+}
+
+func TestDownscaleNoDarkFringe(t *testing.T) {
+	// A white disc on transparent black, the usual export of a logo.
+	wm := image.NewNRGBA(image.Rect(0, 0, 80, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 80; x++ {
+			if dx, dy := x-40, y-40; dx*dx+dy*dy < 30*30 {
+				wm.SetNRGBA(x, y, color.NRGBA{255, 255, 255, 255})
+			}
+		}
+	}
+
+	// Downscaled 4x, every visible pixel is still white once
+	// unpremultiplied, including the partly transparent edge.
+	small := resize(wm, 20, 20)
+	edge := 0
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			c := color.NRGBAModel.Convert(small.At(x, y)).(color.NRGBA)
+			if c.A == 0 {
+				continue
+			}
+			if c.A < 255 {
+				edge++
+			}
+			if c.R < 250 || c.G < 250 || c.B < 250 {
+				t.Fatalf("pixel (%d, %d) = %v, want white", x, y, c)
+			}
+		}
+	}
+	if edge == 0 {
+		t.Fatal("downscaled disc has no partly transparent edge")
+	}
+
+	// Stamped on red, with or without feathering, the edge blends white
+	// into red and never darkens the red.
+	src := fill(40, 40, color.RGBA{255, 0, 0, 255})
+	for _, feather := range []int{0, 2} {
+		out := Apply(src, wm, Options{Position: TopLeft, Opacity: 1, Scale: 0.5, Feather: feather})
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				if c := rgbaAt(out, x, y); c.R < 254 || c.G != c.B {
+					t.Fatalf("feather %d: pixel (%d, %d) = %v, want red blended with white", feather, x, y, c)
+				}
+			}
+		}
+	}
+}
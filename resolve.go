@@ -0,0 +1,286 @@
+package watermark
+
+import (
+	"image"
+	"math"
+)
+
+// ResolvedOptions holds the concrete values Apply derives from Options for a
+// particular source and watermark size.
+type ResolvedOptions struct {
+	Position Position
+	Opacity  float64
+	PaddingX int
+	PaddingY int
+	// Scale is the factor applied to the watermark's native width.
+	Scale float64
+	// Rect is the final watermark rectangle in source coordinates. It may
+	// extend beyond the source bounds, in which case the watermark is
+	// clipped.
+	Rect image.Rectangle
+}
+
+// Resolve computes the concrete placement Apply would use for a source of
+// srcBounds and a watermark of wmBounds. It is pure computation, useful for
+// logging and for checking what a set of options actually does.
+func (o Options) Resolve(srcBounds, wmBounds image.Rectangle) ResolvedOptions {
+	r := ResolvedOptions{
+		Position: o.Position,
+		Opacity:  o.Opacity,
+		PaddingX: o.PaddingX,
+		PaddingY: o.PaddingY,
+		Scale:    1,
+	}
+	if o.RelPaddingX > 0 {
+		r.PaddingX = int(math.Round(o.RelPaddingX * float64(srcBounds.Dx())))
+	}
+	if o.RelPaddingY > 0 {
+		r.PaddingY = int(math.Round(o.RelPaddingY * float64(srcBounds.Dy())))
+	}
+
+	if o.RTL {
+		r.Position = mirrorPosition(r.Position)
+	}
+
+	if r.Opacity <= 0 {
+		r.Opacity = 0.5
+	}
+	if r.Opacity > 1 {
+		r.Opacity = 1
+	}
+	r.Opacity = o.opacityBand(r.Opacity)
+
+	// size is the final watermark size; slot is the area positioned within
+	// the source, which is larger than size only for a fixed box.
+	size := wmBounds.Size()
+	if o.Scale > 0 && !wmBounds.Empty() {
+		size = scaledSize(size, srcBounds, o)
+	}
+	if (o.MaxWidth > 0 || o.MaxHeight > 0) && !wmBounds.Empty() {
+		size = containSize(wmBounds.Size(), maxBox(srcBounds, o), o)
+	}
+	slot := size
+	if o.BoxWidth > 0 && o.BoxHeight > 0 && !wmBounds.Empty() {
+		slot = image.Pt(o.BoxWidth, o.BoxHeight)
+		size = containSize(wmBounds.Size(), slot, o)
+	}
+	if !wmBounds.Empty() {
+		r.Scale = float64(size.X) / float64(wmBounds.Dx())
+	}
+
+	region := image.Rectangle{
+		Min: srcBounds.Min.Add(image.Pt(o.Insets.Left, o.Insets.Top)),
+		Max: srcBounds.Max.Sub(image.Pt(o.Insets.Right, o.Insets.Bottom)),
+	}
+
+	var x, y int
+	switch r.Position {
+	case Center:
+		x = halfFloor(region.Dx() - slot.X)
+		y = halfFloor(region.Dy() - slot.Y)
+	case TopLeft:
+		x = r.PaddingX
+		y = r.PaddingY
+	case TopRight:
+		x = region.Dx() - slot.X - r.PaddingX
+		y = r.PaddingY
+	case BottomLeft:
+		x = r.PaddingX
+		y = region.Dy() - slot.Y - r.PaddingY
+	case BottomRight:
+		x = region.Dx() - slot.X - r.PaddingX
+		y = region.Dy() - slot.Y - r.PaddingY
+	case GoldenTopLeft, GoldenTopRight, GoldenBottomLeft, GoldenBottomRight:
+		p := GoldenPoint(image.Rect(0, 0, region.Dx(), region.Dy()), int(r.Position-GoldenTopLeft))
+		x = p.X - halfFloor(slot.X)
+		y = p.Y - halfFloor(slot.Y)
+	case Polar:
+		bearing := o.Bearing
+		if o.RTL {
+			bearing = -bearing
+		}
+		p := polarPoint(image.Rect(0, 0, region.Dx(), region.Dy()), bearing, o.Distance)
+		x = p.X - halfFloor(slot.X)
+		y = p.Y - halfFloor(slot.Y)
+	}
+
+	if o.Anchor != nil {
+		region.Min = srcBounds.Min
+		x, y = o.Anchor.X, o.Anchor.Y
+	}
+
+	x += o.OffsetX
+	y += o.OffsetY
+
+	min := region.Min.Add(image.Pt(x, y))
+	min = min.Add(image.Pt(halfFloor(slot.X-size.X), halfFloor(slot.Y-size.Y)))
+	r.Rect = image.Rectangle{Min: min, Max: min.Add(size)}
+	return r
+}
+
+// goldenMinor is the smaller golden-ratio section of a unit length, 1/phi^2.
+var goldenMinor = (3 - math.Sqrt(5)) / 2
+
+// GoldenPoint returns one of the four golden-ratio points of bounds, the
+// intersections of lines dividing each dimension at ~0.382 and ~0.618.
+// Corner selects the point: 0 top-left, 1 top-right, 2 bottom-left and
+// 3 bottom-right.
+func GoldenPoint(bounds image.Rectangle, corner int) image.Point {
+	fx, fy := goldenMinor, goldenMinor
+	if corner == 1 || corner == 3 {
+		fx = 1 - goldenMinor
+	}
+	if corner == 2 || corner == 3 {
+		fy = 1 - goldenMinor
+	}
+	return image.Pt(
+		bounds.Min.X+int(math.Round(fx*float64(bounds.Dx()))),
+		bounds.Min.Y+int(math.Round(fy*float64(bounds.Dy()))),
+	)
+}
+
+// polarPoint returns the point at bearing degrees clockwise from straight
+// up and distance half-diagonals from the center of bounds.
+func polarPoint(bounds image.Rectangle, bearing, distance float64) image.Point {
+	rad := bearing * math.Pi / 180
+	d := distance * math.Hypot(float64(bounds.Dx()), float64(bounds.Dy())) / 2
+	return image.Pt(
+		bounds.Min.X+halfFloor(bounds.Dx())+int(math.Round(d*math.Sin(rad))),
+		bounds.Min.Y+halfFloor(bounds.Dy())-int(math.Round(d*math.Cos(rad))),
+	)
+}
+
+// WillClip reports whether a watermark of wmBounds, once resolved against
+// opts, would extend beyond srcBounds and so be clipped. It is cheap enough
+// to call before processing, e.g. to warn users in a UI.
+func WillClip(srcBounds, wmBounds image.Rectangle, opts Options) bool {
+	return !opts.Resolve(srcBounds, wmBounds).Rect.In(srcBounds)
+}
+
+// CSSOverlay returns the box a watermark of wmBounds occupies once resolved
+// against opts, relative to the top-left corner of srcBounds. The values can
+// be used as CSS top, left, width and height to overlay the watermark in a
+// browser exactly where Apply would burn it in.
+func CSSOverlay(srcBounds, wmBounds image.Rectangle, opts Options) (top, left, width, height int) {
+	r := opts.Resolve(srcBounds, wmBounds).Rect.Sub(srcBounds.Min)
+	return r.Min.Y, r.Min.X, r.Dx(), r.Dy()
+}
+
+// containSize returns the largest size with the aspect ratio of size that
+// fits within box, rounded as o.ScaleRounding asks. As with scaledSize, the
+// result never has a side shorter than o.MinWatermarkPx, even if that
+// overflows box.
+func containSize(size, box image.Point, o Options) image.Point {
+	round := o.ScaleRounding.round
+	f := math.Min(float64(box.X)/float64(size.X), float64(box.Y)/float64(size.Y))
+	w := clampInt(round(float64(size.X)*f), 1, box.X)
+	h := clampInt(round(float64(size.Y)*f), 1, box.Y)
+	return minWatermarkSize(size, w, h, o)
+}
+
+// maxBox returns the box in pixels given by o.MaxWidth and o.MaxHeight as
+// fractions of srcBounds. An unset side is effectively unbounded.
+func maxBox(srcBounds image.Rectangle, o Options) image.Point {
+	box := image.Pt(math.MaxInt32, math.MaxInt32)
+	if o.MaxWidth > 0 {
+		box.X = o.ScaleRounding.round(o.MaxWidth * float64(srcBounds.Dx()))
+	}
+	if o.MaxHeight > 0 {
+		box.Y = o.ScaleRounding.round(o.MaxHeight * float64(srcBounds.Dy()))
+	}
+	return box
+}
+
+// scaledSize returns the watermark size after applying o.Scale against the
+// source dimension selected by o.ScaleBy, preserving aspect ratio. The
+// result never has a side shorter than o.MinWatermarkPx.
+func scaledSize(size image.Point, srcBounds image.Rectangle, o Options) image.Point {
+	ref := srcBounds.Dx()
+	switch o.ScaleBy {
+	case Height:
+		ref = srcBounds.Dy()
+	case ShortEdge:
+		if srcBounds.Dy() < ref {
+			ref = srcBounds.Dy()
+		}
+	case LongEdge:
+		if srcBounds.Dy() > ref {
+			ref = srcBounds.Dy()
+		}
+	case Area:
+		ref = int(math.Sqrt(float64(srcBounds.Dx()) * float64(srcBounds.Dy())))
+	}
+
+	round := o.ScaleRounding.round
+	w := round(o.Scale * float64(ref))
+	h := round(float64(w) * float64(size.Y) / float64(size.X))
+	p := minWatermarkSize(size, w, h, o)
+
+	if p.X < 1 {
+		p.X = 1
+	}
+	if p.Y < 1 {
+		p.Y = 1
+	}
+	return p
+}
+
+// minWatermarkSize returns w by h, a scaled size of size, grown with the
+// aspect ratio of size if needed so neither side is shorter than
+// o.MinWatermarkPx.
+func minWatermarkSize(size image.Point, w, h int, o Options) image.Point {
+	if min := o.MinWatermarkPx; min > 0 && (w < min || h < min) {
+		round := o.ScaleRounding.round
+		if size.X <= size.Y {
+			w = min
+			h = round(float64(min) * float64(size.Y) / float64(size.X))
+		} else {
+			h = min
+			w = round(float64(min) * float64(size.X) / float64(size.Y))
+		}
+	}
+	return image.Pt(w, h)
+}
+
+// round rounds v to a whole number of pixels. Floor and ceil allow a
+// little floating-point slack, so 0.3*10 floors and ceils to 3.
+func (r Rounding) round(v float64) int {
+	const eps = 1e-9
+	switch r {
+	case RoundFloor:
+		return int(math.Floor(v + eps))
+	case RoundCeil:
+		return int(math.Ceil(v - eps))
+	}
+	return int(math.Round(v))
+}
+
+// halfFloor halves n, rounding towards negative infinity. Centering with it
+// always gives the odd leftover pixel to the right or bottom margin, even
+// when the watermark is larger than the source and n is negative.
+func halfFloor(n int) int {
+	return n >> 1
+}
+
+// mirrorPosition swaps the left and right variants of p.
+func mirrorPosition(p Position) Position {
+	switch p {
+	case TopLeft:
+		return TopRight
+	case TopRight:
+		return TopLeft
+	case BottomLeft:
+		return BottomRight
+	case BottomRight:
+		return BottomLeft
+	case GoldenTopLeft:
+		return GoldenTopRight
+	case GoldenTopRight:
+		return GoldenTopLeft
+	case GoldenBottomLeft:
+		return GoldenBottomRight
+	case GoldenBottomRight:
+		return GoldenBottomLeft
+	}
+	return p
+}
@@ -0,0 +1,395 @@
+package watermark
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestScaleByShortEdge(t *testing.T) {
+	wm := image.Rect(0, 0, 200, 100)
+	opts := Options{Scale: 0.25, ScaleBy: ShortEdge}
+
+	portrait := opts.Resolve(image.Rect(0, 0, 400, 800), wm).Rect.Size()
+	landscape := opts.Resolve(image.Rect(0, 0, 800, 400), wm).Rect.Size()
+	if portrait != landscape {
+		t.Errorf("portrait size %v != landscape size %v", portrait, landscape)
+	}
+	if want := image.Pt(100, 50); portrait != want {
+		t.Errorf("size = %v, want %v", portrait, want)
+	}
+}
+
+func TestScaleBy(t *testing.T) {
+	src := image.Rect(0, 0, 400, 800)
+	wm := image.Rect(0, 0, 100, 100)
+	tests := []struct {
+		by   ScaleBy
+		want int
+	}{
+		{Width, 40},
+		{Height, 80},
+		{ShortEdge, 40},
+		{LongEdge, 80},
+	}
+	for _, tt := range tests {
+		opts := Options{Scale: 0.1, ScaleBy: tt.by}
+		if got := opts.Resolve(src, wm).Rect.Dx(); got != tt.want {
+			t.Errorf("ScaleBy %d: width = %d, want %d", tt.by, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	opts := Options{Position: BottomRight, Opacity: 0.8, PaddingX: 10, PaddingY: 20, Scale: 0.25}
+	r := opts.Resolve(image.Rect(0, 0, 800, 600), image.Rect(0, 0, 100, 50))
+
+	want := ResolvedOptions{
+		Position: BottomRight,
+		Opacity:  0.8,
+		PaddingX: 10,
+		PaddingY: 20,
+		Scale:    2,
+		Rect:     image.Rect(590, 480, 790, 580),
+	}
+	if r != want {
+		t.Errorf("Resolve = %+v, want %+v", r, want)
+	}
+}
+
+func TestResolveRelPadding(t *testing.T) {
+	opts := Options{Position: BottomRight, PaddingX: 10, PaddingY: 10, RelPaddingX: 0.05, RelPaddingY: 0.025}
+	r := opts.Resolve(image.Rect(0, 0, 800, 600), image.Rect(0, 0, 100, 50))
+
+	if r.PaddingX != 40 || r.PaddingY != 15 {
+		t.Errorf("padding = %d,%d, want 40,15", r.PaddingX, r.PaddingY)
+	}
+	if want := image.Rect(660, 535, 760, 585); r.Rect != want {
+		t.Errorf("rect = %v, want %v", r.Rect, want)
+	}
+}
+
+func TestResolveDefaults(t *testing.T) {
+	tests := []struct {
+		opacity, want float64
+	}{
+		{0, 0.5},
+		{-1, 0.5},
+		{0.3, 0.3},
+		{2, 1},
+	}
+	for _, tt := range tests {
+		r := Options{Opacity: tt.opacity}.Resolve(image.Rect(0, 0, 10, 10), image.Rect(0, 0, 4, 4))
+		if r.Opacity != tt.want {
+			t.Errorf("Opacity %v resolved to %v, want %v", tt.opacity, r.Opacity, tt.want)
+		}
+	}
+}
+
+func TestResolveOffset(t *testing.T) {
+	src, wm := image.Rect(0, 0, 200, 100), image.Rect(0, 0, 40, 20)
+	opts := Options{Position: BottomRight, PaddingX: 10, PaddingY: 10}
+	base := opts.Resolve(src, wm).Rect
+
+	opts.OffsetX = -20
+	got := opts.Resolve(src, wm).Rect
+	if want := base.Add(image.Pt(-20, 0)); got != want {
+		t.Errorf("OffsetX -20: rect = %v, want %v", got, want)
+	}
+}
+
+func TestResolveInsets(t *testing.T) {
+	src, wm := image.Rect(0, 0, 300, 600), image.Rect(0, 0, 40, 20)
+	opts := Options{Position: TopLeft, PaddingX: 10, PaddingY: 10, Insets: Insets{Top: 50}}
+
+	r := opts.Resolve(src, wm).Rect
+	if want := image.Rect(10, 60, 50, 80); r != want {
+		t.Errorf("rect = %v, want %v", r, want)
+	}
+	if r.Min.Y < opts.Insets.Top {
+		t.Errorf("watermark top %d lies under the %dpx inset", r.Min.Y, opts.Insets.Top)
+	}
+
+	opts.Position = BottomRight
+	opts.Insets = Insets{Right: 30, Bottom: 40}
+	if r, want := opts.Resolve(src, wm).Rect, image.Rect(220, 530, 260, 550); r != want {
+		t.Errorf("BottomRight rect = %v, want %v", r, want)
+	}
+}
+
+func TestResolveMinWatermarkPx(t *testing.T) {
+	src, wm := image.Rect(0, 0, 100, 80), image.Rect(0, 0, 200, 100)
+	opts := Options{Scale: 0.1}
+
+	if got := opts.Resolve(src, wm).Rect.Size(); got != image.Pt(10, 5) {
+		t.Fatalf("unclamped size = %v, want 10x5", got)
+	}
+	opts.MinWatermarkPx = 24
+	if got := opts.Resolve(src, wm).Rect.Size(); got != image.Pt(48, 24) {
+		t.Errorf("clamped size = %v, want 48x24", got)
+	}
+}
+
+func TestResolveCenterOdd(t *testing.T) {
+	tests := []struct {
+		src, wm int
+	}{
+		{101, 20}, // odd leftover
+		{100, 20}, // even leftover
+		{10, 21},  // watermark wider than the source
+	}
+	for _, tt := range tests {
+		r := Options{Position: Center}.Resolve(image.Rect(0, 0, tt.src, tt.src), image.Rect(0, 0, tt.wm, tt.wm)).Rect
+		left, right := r.Min.X, tt.src-r.Max.X
+		top, bottom := r.Min.Y, tt.src-r.Max.Y
+		// The odd pixel, if any, goes to the right and bottom margins.
+		if d := right - left; d != 0 && d != 1 {
+			t.Errorf("src %d, wm %d: left margin %d, right margin %d", tt.src, tt.wm, left, right)
+		}
+		if d := bottom - top; d != 0 && d != 1 {
+			t.Errorf("src %d, wm %d: top margin %d, bottom margin %d", tt.src, tt.wm, top, bottom)
+		}
+	}
+}
+
+func TestResolveRTL(t *testing.T) {
+	src, wm := image.Rect(0, 0, 200, 100), image.Rect(0, 0, 40, 20)
+	opts := Options{Position: BottomRight, PaddingX: 10, PaddingY: 10, RTL: true}
+
+	r := opts.Resolve(src, wm)
+	if r.Position != BottomLeft {
+		t.Errorf("Position = %v, want BottomLeft", r.Position)
+	}
+	if want := image.Rect(10, 70, 50, 90); r.Rect != want {
+		t.Errorf("rect = %v, want %v", r.Rect, want)
+	}
+
+	opts.Position = Center
+	if got, want := opts.Resolve(src, wm).Rect, (Options{Position: Center}).Resolve(src, wm).Rect; got != want {
+		t.Errorf("RTL moved a centered watermark to %v, want %v", got, want)
+	}
+}
+
+func TestWillClip(t *testing.T) {
+	src := image.Rect(0, 0, 100, 100)
+	opts := Options{Position: BottomRight, PaddingX: 10, PaddingY: 10}
+
+	if WillClip(src, image.Rect(0, 0, 20, 20), opts) {
+		t.Error("small watermark: WillClip = true, want false")
+	}
+	if !WillClip(src, image.Rect(0, 0, 150, 20), opts) {
+		t.Error("oversized watermark: WillClip = false, want true")
+	}
+	// Scaling is taken into account.
+	opts.Scale = 1.5
+	if !WillClip(src, image.Rect(0, 0, 20, 20), opts) {
+		t.Error("watermark scaled beyond the source: WillClip = false, want true")
+	}
+}
+
+func TestGoldenPoint(t *testing.T) {
+	bounds := image.Rect(100, 50, 1100, 550)
+	tests := []struct {
+		corner int
+		fx, fy float64
+	}{
+		{0, 0.382, 0.382},
+		{1, 0.618, 0.382},
+		{2, 0.382, 0.618},
+		{3, 0.618, 0.618},
+	}
+	for _, tt := range tests {
+		p := GoldenPoint(bounds, tt.corner)
+		fx := float64(p.X-bounds.Min.X) / float64(bounds.Dx())
+		fy := float64(p.Y-bounds.Min.Y) / float64(bounds.Dy())
+		if math.Abs(fx-tt.fx) > 0.002 || math.Abs(fy-tt.fy) > 0.002 {
+			t.Errorf("corner %d: point at (%.3f, %.3f), want (%v, %v)", tt.corner, fx, fy, tt.fx, tt.fy)
+		}
+	}
+}
+
+func TestResolveGolden(t *testing.T) {
+	src := image.Rect(0, 0, 1000, 500)
+	r := Options{Position: GoldenBottomRight}.Resolve(src, image.Rect(0, 0, 40, 20)).Rect
+	center := r.Min.Add(r.Size().Div(2))
+	if want := GoldenPoint(src, 3); center != want {
+		t.Errorf("watermark centered at %v, want %v", center, want)
+	}
+}
+
+func TestResolveBox(t *testing.T) {
+	src := image.Rect(0, 0, 400, 300)
+	opts := Options{Position: TopLeft, PaddingX: 10, PaddingY: 10, BoxWidth: 100, BoxHeight: 50}
+
+	tests := []struct {
+		name string
+		wm   image.Rectangle
+		want image.Rectangle
+	}{
+		// 4:1 fills the box width and is centered vertically.
+		{"wide", image.Rect(0, 0, 200, 50), image.Rect(10, 22, 110, 47)},
+		// 1:2 fills the box height and is centered horizontally.
+		{"tall", image.Rect(0, 0, 30, 60), image.Rect(47, 10, 72, 60)},
+	}
+	for _, tt := range tests {
+		r := opts.Resolve(src, tt.wm).Rect
+		if r != tt.want {
+			t.Errorf("%s: rect = %v, want %v", tt.name, r, tt.want)
+		}
+		if box := image.Rect(10, 10, 110, 60); !r.In(box) {
+			t.Errorf("%s: rect %v outside the box %v", tt.name, r, box)
+		}
+		// Aspect ratio is kept to within a pixel of rounding.
+		if got, want := float64(r.Dx())/float64(r.Dy()), float64(tt.wm.Dx())/float64(tt.wm.Dy()); math.Abs(got-want) > 0.05*want {
+			t.Errorf("%s: aspect %.2f, want %.2f", tt.name, got, want)
+		}
+	}
+}
+
+func TestScaleByArea(t *testing.T) {
+	wm := image.Rect(0, 0, 100, 50)
+	opts := Options{Scale: 0.1, ScaleBy: Area}
+
+	small := opts.Resolve(image.Rect(0, 0, 800, 600), wm).Rect.Dx()
+	large := opts.Resolve(image.Rect(0, 0, 1600, 1200), wm).Rect.Dx()
+	// Four times the area gives about twice the width, not four times.
+	if ratio := float64(large) / float64(small); math.Abs(ratio-2) > 0.02 {
+		t.Errorf("width %d on 4x the area of %d: ratio %.3f, want 2", large, small, ratio)
+	}
+	if want := int(math.Sqrt(800*600) * 0.1); small < want-1 || small > want+1 {
+		t.Errorf("width = %d, want %d", small, want)
+	}
+}
+
+func TestResolveOpacityBand(t *testing.T) {
+	src, wm := image.Rect(0, 0, 100, 100), image.Rect(0, 0, 20, 20)
+	tests := []struct {
+		opacity, min, max, want float64
+	}{
+		{0.9, 0.2, 0.6, 0.6},
+		{0.1, 0.2, 0.6, 0.2},
+		{0.4, 0.2, 0.6, 0.4},
+		{0, 0.7, 0, 0.7}, // the default opacity is banded too
+		{0.5, 0.8, 0.3, 0.3},
+	}
+	for _, tt := range tests {
+		opts := Options{Position: Center, Opacity: tt.opacity, OpacityMin: tt.min, OpacityMax: tt.max}
+		if got := opts.Resolve(src, wm).Opacity; got != tt.want {
+			t.Errorf("Opacity %v in [%v, %v]: resolved %v, want %v", tt.opacity, tt.min, tt.max, got, tt.want)
+		}
+
+		// The SVG overlay declares the same opacity Apply stamps with.
+		svg, err := SVGOverlay(src, wm, "mark.png", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attr := fmt.Sprintf(`opacity="%v"`, tt.want); !strings.Contains(string(svg), attr) {
+			t.Errorf("Opacity %v in [%v, %v]: SVG lacks %s", tt.opacity, tt.min, tt.max, attr)
+		}
+	}
+}
+
+func TestCSSOverlay(t *testing.T) {
+	wm := fill(40, 20, color.White)
+	for _, pos := range []Position{TopLeft, Center, BottomRight} {
+		opts := Options{Position: pos, Opacity: 1, PaddingX: 7, PaddingY: 5, Scale: 0.2}
+
+		// A source whose bounds do not start at the origin.
+		src := fill(300, 200, color.Black).SubImage(image.Rect(50, 30, 300, 200))
+		top, left, width, height := CSSOverlay(src.Bounds(), wm.Bounds(), opts)
+
+		box := image.Rect(left, top, left+width, top+height).Add(src.Bounds().Min)
+		if r := opts.Resolve(src.Bounds(), wm.Bounds()).Rect; box != r {
+			t.Errorf("position %v: CSS box %v, want resolved %v", pos, box, r)
+		}
+		if r := changed(src, Apply(src, wm, opts)); box != r {
+			t.Errorf("position %v: CSS box %v, want burned-in %v", pos, box, r)
+		}
+	}
+}
+
+func TestResolvePolar(t *testing.T) {
+	src, wm := image.Rect(0, 0, 400, 300), image.Rect(0, 0, 20, 10)
+	center := image.Pt(200, 150)
+	// Half of the 250px half-diagonal.
+	const d = 125
+
+	tests := []struct {
+		bearing float64
+		want    image.Point
+	}{
+		{0, center.Add(image.Pt(0, -d))},
+		{90, center.Add(image.Pt(d, 0))},
+		{180, center.Add(image.Pt(0, d))},
+		{270, center.Add(image.Pt(-d, 0))},
+	}
+	for _, tt := range tests {
+		r := Options{Position: Polar, Bearing: tt.bearing, Distance: 0.5}.Resolve(src, wm).Rect
+		if got := r.Min.Add(r.Size().Div(2)); got != tt.want {
+			t.Errorf("bearing %v: watermark centered at %v, want %v", tt.bearing, got, tt.want)
+		}
+	}
+}
+
+func TestScaleRounding(t *testing.T) {
+	wm := image.Rect(0, 0, 100, 40)
+	tests := []struct {
+		srcWidth int
+		rounding Rounding
+		want     int
+	}{
+		{105, RoundNearest, 11}, // 10.5 rounds up rather than truncating
+		{104, RoundNearest, 10}, // 10.4
+		{105, RoundFloor, 10},
+		{104, RoundCeil, 11},
+		{100, RoundCeil, 10}, // exact sizes are left alone
+	}
+	for _, tt := range tests {
+		opts := Options{Scale: 0.1, ScaleRounding: tt.rounding}
+		if got := opts.Resolve(image.Rect(0, 0, tt.srcWidth, 100), wm).Rect.Dx(); got != tt.want {
+			t.Errorf("source width %d, rounding %d: width = %d, want %d", tt.srcWidth, tt.rounding, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMaxBox(t *testing.T) {
+	src := image.Rect(0, 0, 1000, 500)
+	opts := Options{MaxWidth: 0.2, MaxHeight: 0.2} // a 200x100 box
+
+	tests := []struct {
+		name string
+		wm   image.Rectangle
+		want image.Point
+	}{
+		// A tall logo is limited by the box height.
+		{"tall", image.Rect(0, 0, 50, 200), image.Pt(25, 100)},
+		// A wide logo is limited by the box width.
+		{"wide", image.Rect(0, 0, 400, 100), image.Pt(200, 50)},
+		// Small logos are scaled up to fill the box.
+		{"small", image.Rect(0, 0, 20, 20), image.Pt(100, 100)},
+	}
+	for _, tt := range tests {
+		if got := opts.Resolve(src, tt.wm).Rect.Size(); got != tt.want {
+			t.Errorf("%s: size = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Fitted sizes are rounded as ScaleRounding asks: a 3x7 logo fits the
+	// box at 42.86x100.
+	for rounding, want := range map[Rounding]int{RoundNearest: 43, RoundFloor: 42, RoundCeil: 43} {
+		opts := opts
+		opts.ScaleRounding = rounding
+		if got := opts.Resolve(src, image.Rect(0, 0, 3, 7)).Rect.Dx(); got != want {
+			t.Errorf("rounding %v: width = %d, want %d", rounding, got, want)
+		}
+	}
+
+	// MinWatermarkPx wins over the box, as it does over Scale.
+	opts.MinWatermarkPx = 80
+	if got := opts.Resolve(src, image.Rect(0, 0, 400, 100)).Rect.Size(); got != image.Pt(320, 80) {
+		t.Errorf("min 80: size = %v, want 320x80", got)
+	}
+}
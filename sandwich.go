@@ -0,0 +1,20 @@
+package watermark
+
+import (
+	"image"
+	"image/draw"
+)
+
+// ApplySandwich composites under, then src, then over in a single pass: the
+// under watermark shows only through transparent or translucent parts of
+// src, while the over watermark sits on top like a normal Apply. Each
+// watermark is placed with its own options.
+func ApplySandwich(src, under, over image.Image, underOpts, overOpts Options) image.Image {
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+
+	applyTo(dst, under, underOpts)
+	draw.Draw(dst, b, src, b.Min, draw.Over)
+	applyTo(dst, over, overOpts)
+	return dst
+}
@@ -0,0 +1,35 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestApplySandwich(t *testing.T) {
+	// An opaque gray photo with a transparent hole in the middle.
+	src := fill(60, 60, color.Gray{128})
+	draw.Draw(src, image.Rect(20, 20, 40, 40), image.Transparent, image.Point{}, draw.Src)
+
+	under := fill(60, 60, color.RGBA{255, 0, 0, 255})
+	over := fill(10, 10, color.RGBA{0, 0, 255, 255})
+	out := ApplySandwich(src, under, over,
+		Options{Position: TopLeft, Opacity: 1},
+		Options{Position: Center, Opacity: 1})
+
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"under covered by the photo", 5, 5, color.RGBA{128, 128, 128, 255}},
+		{"under through the hole", 22, 22, color.RGBA{255, 0, 0, 255}},
+		{"over on top", 30, 30, color.RGBA{0, 0, 255, 255}},
+	}
+	for _, tt := range tests {
+		if got := rgbaAt(out, tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: pixel (%d, %d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
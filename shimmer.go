@@ -0,0 +1,50 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// ApplyShimmerFrames returns frames copies of src watermarked as Apply
+// does, with a bright diagonal band sweeping across the watermark from left
+// to right over the sequence, for encoding as an animated GIF or WebP. The
+// band starts just off the watermark's left edge and the sequence loops
+// seamlessly. Pixels outside the watermark are the same in every frame.
+func ApplyShimmerFrames(src, watermark image.Image, opts Options, frames int) []image.Image {
+	if frames <= 0 {
+		return nil
+	}
+
+	b := watermark.Bounds()
+	band := math.Max(float64(b.Dx())/6, 1)
+	span := float64(b.Dx()) + float64(b.Dy())/2 + 2*band
+
+	out := make([]image.Image, frames)
+	for i := range out {
+		center := -band + span*float64(i)/float64(frames)
+		out[i] = Apply(src, shimmer(watermark, center, band), opts)
+	}
+	return out
+}
+
+// shimmer returns a copy of img brightened along a band of half-width band
+// centered on the slanted line x + y/2 = center, fading linearly to nothing
+// at its edges. Alpha is kept so the watermark's shape is preserved.
+func shimmer(img image.Image, center, band float64) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			d := math.Abs(float64(x)+float64(y)/2-center) / band
+			if d < 1 {
+				k := 0.8 * (1 - d)
+				lift := func(v uint8) uint8 { return uint8(math.Round(float64(v) + k*float64(255-v))) }
+				c.R, c.G, c.B = lift(c.R), lift(c.G), lift(c.B)
+			}
+			dst.SetNRGBA(x, y, c)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,47 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyShimmerFrames(t *testing.T) {
+	src := photo(120, 80)
+	wm := fill(60, 20, color.RGBA{60, 60, 60, 255})
+	opts := Options{Position: TopLeft, Opacity: 1}
+	frames := ApplyShimmerFrames(src, wm, opts, 8)
+	if len(frames) != 8 {
+		t.Fatalf("%d frames, want 8", len(frames))
+	}
+
+	// brightest returns the x of the brightest pixel along the watermark's
+	// top row, or -1 if the band is off the watermark.
+	brightest := func(img image.Image) int {
+		best, at := uint8(60), -1
+		for x := 0; x < 60; x++ {
+			if v := rgbaAt(img, x, 0).R; v > best {
+				best, at = v, x
+			}
+		}
+		return at
+	}
+
+	prev, seen := -1, 0
+	for i, f := range frames {
+		if x := brightest(f); x >= 0 {
+			if x <= prev {
+				t.Errorf("frame %d: band at x=%d, want right of %d", i, x, prev)
+			}
+			prev = x
+			seen++
+		}
+		// Only the watermark changes from frame to frame.
+		if r := changed(frames[0], f); !r.In(image.Rect(0, 0, 60, 20)) {
+			t.Errorf("frame %d: changed %v outside the watermark", i, r)
+		}
+	}
+	if seen < 3 {
+		t.Errorf("band visible in %d frames, want it to sweep across several", seen)
+	}
+}
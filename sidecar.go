@@ -0,0 +1,109 @@
+package watermark
+
+import (
+	"encoding/json"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sidecar is the JSON document SaveWithSidecar writes next to an image to
+// record how it was watermarked.
+type Sidecar struct {
+	Position Position `json:"position"`
+	Opacity  float64  `json:"opacity"`
+	PaddingX int      `json:"paddingX"`
+	PaddingY int      `json:"paddingY"`
+	OffsetX  int      `json:"offsetX"`
+	OffsetY  int      `json:"offsetY"`
+	ScaleBy  ScaleBy  `json:"scaleBy"`
+	// Scale and Rect are the resolved scale factor and watermark box, and
+	// are only recorded when the watermark size is known.
+	Scale         float64      `json:"scale,omitempty"`
+	Rect          *SidecarRect `json:"rect,omitempty"`
+	WatermarkHash string       `json:"watermarkHash,omitempty"`
+	Width         int          `json:"width"`
+	Height        int          `json:"height"`
+}
+
+// SidecarRect is the watermark box recorded in a Sidecar, relative to the
+// top-left corner of the image.
+type SidecarRect struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// SaveWithSidecar saves img to path, as PNG or JPEG depending on the file
+// extension and declaring opts.OutputDPI if set, and writes a "<path>.json"
+// sidecar describing the resolved options, the watermark hash and the
+// output dimensions. Use SaveWithSidecarBounds to also record the resolved
+// scale and watermark box.
+func SaveWithSidecar(img image.Image, path string, opts Options, wmHash string, quality int) error {
+	return saveWithSidecar(img, path, opts, image.Rectangle{}, wmHash, quality)
+}
+
+// SaveWithSidecarBounds is like SaveWithSidecar, but also records the
+// resolved scale and watermark box for a watermark of wmBounds, the size it
+// had before scaling.
+func SaveWithSidecarBounds(img image.Image, path string, opts Options, wmBounds image.Rectangle, wmHash string, quality int) error {
+	return saveWithSidecar(img, path, opts, wmBounds, wmHash, quality)
+}
+
+// saveWithSidecar saves img and its sidecar, leaving out the scale and box
+// if wmBounds is empty.
+func saveWithSidecar(img image.Image, path string, opts Options, wmBounds image.Rectangle, wmHash string, quality int) error {
+	if err := saveFile(img, path, quality, opts.OutputDPI); err != nil {
+		return err
+	}
+
+	b := img.Bounds()
+	r := opts.Resolve(b, wmBounds)
+	sc := Sidecar{
+		Position:      r.Position,
+		Opacity:       r.Opacity,
+		PaddingX:      r.PaddingX,
+		PaddingY:      r.PaddingY,
+		OffsetX:       opts.OffsetX,
+		OffsetY:       opts.OffsetY,
+		ScaleBy:       opts.ScaleBy,
+		WatermarkHash: wmHash,
+		Width:         b.Dx(),
+		Height:        b.Dy(),
+	}
+	if !wmBounds.Empty() {
+		box := r.Rect.Sub(b.Min)
+		sc.Scale = r.Scale
+		sc.Rect = &SidecarRect{Left: box.Min.X, Top: box.Min.Y, Width: box.Dx(), Height: box.Dy()}
+	}
+	data, err := json.MarshalIndent(sc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".json", data, 0o644)
+}
+
+// saveFile encodes img to path in the format implied by its extension,
+// declaring a resolution of dpi if it is positive.
+func saveFile(img image.Image, path string, quality, dpi int) (err error) {
+	if !isSupportedImage(path) {
+		return ErrUnsupportedFormat
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		return SavePNGDPI(img, f, dpi)
+	}
+	return SaveJPEGDPI(img, f, quality, dpi)
+}
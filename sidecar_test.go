@@ -0,0 +1,88 @@
+package watermark
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveWithSidecar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.png")
+	opts := Options{Position: BottomRight, Opacity: 0.7, PaddingX: 10, PaddingY: 5, RTL: true, Scale: 0.1}
+	wm := image.Rect(0, 0, 40, 20)
+	img := fill(200, 100, color.Black)
+
+	if err := SaveWithSidecarBounds(img, path, opts, wm, "abc123", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := png.Decode(f); err != nil {
+		t.Errorf("decoding image: %v", err)
+	}
+
+	data, err := os.ReadFile(path + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Sidecar
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := Sidecar{
+		Position:      BottomLeft,
+		Opacity:       0.7,
+		PaddingX:      10,
+		PaddingY:      5,
+		Scale:         0.5,
+		WatermarkHash: "abc123",
+		Width:         200,
+		Height:        100,
+	}
+	if got.Rect == nil {
+		t.Fatal("sidecar has no rect")
+	}
+	if r, want := *got.Rect, (SidecarRect{Left: 10, Top: 85, Width: 20, Height: 10}); r != want {
+		t.Errorf("rect = %+v, want %+v", r, want)
+	}
+	got.Rect = nil
+	if got != want {
+		t.Errorf("sidecar = %+v, want %+v", got, want)
+	}
+}
+
+func TestSaveWithSidecarNoBounds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.png")
+	opts := Options{Position: TopLeft, Opacity: 0.4, PaddingX: 3, Scale: 0.1}
+
+	if err := SaveWithSidecar(fill(20, 10, color.Black), path, opts, "abc123", 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Sidecar
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := Sidecar{Position: TopLeft, Opacity: 0.4, PaddingX: 3, WatermarkHash: "abc123", Width: 20, Height: 10}
+	if got.Rect != nil || got != want {
+		t.Errorf("sidecar = %+v, want %+v", got, want)
+	}
+}
+
+func TestSaveWithSidecarUnsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.gif")
+	if err := SaveWithSidecar(fill(4, 4, color.Black), path, Options{}, "", 0); err != ErrUnsupportedFormat {
+		t.Errorf("error = %v, want ErrUnsupportedFormat", err)
+	}
+}
@@ -0,0 +1,93 @@
+package watermark
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"image"
+	"image/draw"
+)
+
+// signatureBits is the number of pixels carrying the invisible signature,
+// one HMAC-SHA256 bit per pixel.
+const signatureBits = sha256.Size * 8
+
+// ApplyIdempotent applies a watermark and signs the result with an invisible
+// HMAC keyed by key. If src already carries a valid signature for key, it is
+// returned unchanged along with false, so retried pipelines never
+// watermark the same image twice.
+//
+// The signature lives in the least significant bit of the blue channel, so
+// it only survives lossless encodings such as PNG.
+func ApplyIdempotent(src, watermark image.Image, opts Options, key []byte) (image.Image, bool, error) {
+	if verifySignature(src, key) {
+		return src, false, nil
+	}
+
+	signed, err := sign(Apply(src, watermark, opts), key)
+	if err != nil {
+		return nil, false, err
+	}
+	return signed, true, nil
+}
+
+// sign returns a copy of img carrying an invisible HMAC of its pixels.
+func sign(img image.Image, key []byte) (*image.RGBA, error) {
+	b := img.Bounds()
+	if b.Dx()*b.Dy() < signatureBits {
+		return nil, ErrImageTooSmall
+	}
+
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, img, b.Min, draw.Src)
+
+	mac := signatureMAC(dst, key)
+	for i := 0; i < signatureBits; i++ {
+		off := signatureOffset(dst, i)
+		bit := mac[i/8] >> (7 - uint(i%8)) & 1
+		dst.Pix[off] = dst.Pix[off]&^1 | bit
+	}
+	return dst, nil
+}
+
+// verifySignature reports whether img carries a valid signature for key.
+func verifySignature(img image.Image, key []byte) bool {
+	b := img.Bounds()
+	if b.Dx()*b.Dy() < signatureBits {
+		return false
+	}
+
+	rgba := image.NewRGBA(b)
+	draw.Draw(rgba, b, img, b.Min, draw.Src)
+
+	got := make([]byte, sha256.Size)
+	for i := 0; i < signatureBits; i++ {
+		got[i/8] |= (rgba.Pix[signatureOffset(rgba, i)] & 1) << (7 - uint(i%8))
+	}
+	return hmac.Equal(got, signatureMAC(rgba, key))
+}
+
+// signatureMAC computes the HMAC of img's pixels, ignoring the bits that
+// carry the signature itself.
+func signatureMAC(img *image.RGBA, key []byte) []byte {
+	b := img.Bounds()
+	row := make([]byte, 4*b.Dx())
+	h := hmac.New(sha256.New, key)
+
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		start := img.PixOffset(b.Min.X, y)
+		copy(row, img.Pix[start:start+len(row)])
+		for x := 0; x < b.Dx() && i < signatureBits; x, i = x+1, i+1 {
+			row[4*x+2] &^= 1
+		}
+		h.Write(row)
+	}
+	return h.Sum(nil)
+}
+
+// signatureOffset returns the Pix offset of the blue sample carrying
+// signature bit i, counting pixels in row-major order.
+func signatureOffset(img *image.RGBA, i int) int {
+	b := img.Bounds()
+	return img.PixOffset(b.Min.X+i%b.Dx(), b.Min.Y+i/b.Dx()) + 2
+}
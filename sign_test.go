@@ -0,0 +1,42 @@
+package watermark
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestApplyIdempotent(t *testing.T) {
+	src := fill(32, 32, color.RGBA{40, 80, 120, 255})
+	wm := fill(8, 8, color.White)
+	key := []byte("secret")
+
+	first, applied, err := ApplyIdempotent(src, wm, DefaultOptions(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applied {
+		t.Fatal("first call: applied = false, want true")
+	}
+
+	second, applied, err := ApplyIdempotent(first, wm, DefaultOptions(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied {
+		t.Error("second call: applied = true, want false")
+	}
+	if second != first {
+		t.Error("second call did not return its input unchanged")
+	}
+
+	// A different key does not recognise the signature.
+	if _, applied, _ := ApplyIdempotent(first, wm, DefaultOptions(), []byte("other")); !applied {
+		t.Error("other key: applied = false, want true")
+	}
+}
+
+func TestApplyIdempotentTooSmall(t *testing.T) {
+	if _, _, err := ApplyIdempotent(fill(8, 8, color.Black), fill(2, 2, color.White), DefaultOptions(), []byte("k")); err != ErrImageTooSmall {
+		t.Errorf("error = %v, want ErrImageTooSmall", err)
+	}
+}
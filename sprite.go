@@ -0,0 +1,36 @@
+package watermark
+
+import (
+	"image"
+	"image/draw"
+)
+
+// ApplySprite treats sheet as a cols x rows grid of equally sized cells,
+// such as a video thumbnail sprite sheet, and stamps the watermark within
+// each cell. Placement, scaling and padding in opts are relative to the
+// cell, not the whole sheet. Any remainder pixels beyond the last full
+// column or row are left untouched.
+func ApplySprite(sheet image.Image, cols, rows int, watermark image.Image, opts Options) image.Image {
+	dst := newCanvas(sheet)
+	if cols <= 0 || rows <= 0 {
+		return dst
+	}
+
+	b := dst.Bounds()
+	cellW, cellH := b.Dx()/cols, b.Dy()/rows
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			min := b.Min.Add(image.Pt(col*cellW, row*cellH))
+			cell := image.Rectangle{Min: min, Max: min.Add(image.Pt(cellW, cellH))}
+			applyTo(subCanvas(dst, cell), watermark, opts)
+		}
+	}
+	return dst
+}
+
+// subCanvas returns the part of canvas inside r, sharing its pixels.
+func subCanvas(canvas draw.Image, r image.Rectangle) draw.Image {
+	return canvas.(interface {
+		SubImage(image.Rectangle) image.Image
+	}).SubImage(r).(draw.Image)
+}
@@ -0,0 +1,21 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplySprite(t *testing.T) {
+	sheet := fill(200, 100, color.Black)
+	out := ApplySprite(sheet, 2, 2, fill(10, 10, color.White), Options{Position: BottomRight, Opacity: 1, PaddingX: 5, PaddingY: 5}).(*image.RGBA)
+
+	// Each 100x50 cell gets a stamp in its own bottom-right corner.
+	for _, min := range []image.Point{{0, 0}, {100, 0}, {0, 50}, {100, 50}} {
+		cell := image.Rectangle{Min: min, Max: min.Add(image.Pt(100, 50))}
+		want := image.Rect(85, 35, 95, 45).Add(min)
+		if got := changed(sheet.SubImage(cell), out.SubImage(cell)); got != want {
+			t.Errorf("cell %v: changed %v, want %v", cell, got, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package watermark
+
+import "image"
+
+// ssimWindow is the side length of the square windows SSIM is averaged over.
+const ssimWindow = 8
+
+// ssim returns the mean structural similarity of the luma channels of a and
+// b, computed over non-overlapping windows. Both images are compared over
+// the size of a; 1 means identical.
+func ssim(a, b image.Image) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+
+	ab, bb := a.Bounds(), b.Bounds()
+	w, h := ab.Dx(), ab.Dy()
+	if w == 0 || h == 0 {
+		return 1
+	}
+
+	var total float64
+	var windows int
+	for wy := 0; wy < h; wy += ssimWindow {
+		for wx := 0; wx < w; wx += ssimWindow {
+			var sumA, sumB, sumAA, sumBB, sumAB, n float64
+			for y := wy; y < wy+ssimWindow && y < h; y++ {
+				for x := wx; x < wx+ssimWindow && x < w; x++ {
+					la := luma(a.At(ab.Min.X+x, ab.Min.Y+y).RGBA())
+					lb := luma(b.At(bb.Min.X+x, bb.Min.Y+y).RGBA())
+					sumA += la
+					sumB += lb
+					sumAA += la * la
+					sumBB += lb * lb
+					sumAB += la * lb
+					n++
+				}
+			}
+
+			meanA, meanB := sumA/n, sumB/n
+			varA := sumAA/n - meanA*meanA
+			varB := sumBB/n - meanB*meanB
+			cov := sumAB/n - meanA*meanB
+
+			total += ((2*meanA*meanB + c1) * (2*cov + c2)) /
+				((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			windows++
+		}
+	}
+	return total / float64(windows)
+}
+
+// luma converts 16-bit premultiplied RGBA components to Rec. 601 luma in
+// the 0-255 range.
+func luma(r, g, b, _ uint32) float64 {
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+}
@@ -0,0 +1,32 @@
+package watermark
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"strconv"
+)
+
+// SVGOverlay returns an SVG document the size of srcBounds that shows the
+// watermark referenced by watermarkHref where, and at the size and opacity,
+// Apply would burn in a watermark of wmBounds. Designers can then adjust the
+// placement in a vector editor, or layer the document over the source on
+// the web.
+func SVGOverlay(srcBounds, wmBounds image.Rectangle, watermarkHref string, opts Options) ([]byte, error) {
+	top, left, width, height := CSSOverlay(srcBounds, wmBounds, opts)
+	opacity := opts.Resolve(srcBounds, wmBounds).Opacity
+
+	var href bytes.Buffer
+	if err := xml.EscapeText(&href, []byte(watermarkHref)); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		srcBounds.Dx(), srcBounds.Dy(), srcBounds.Dx(), srcBounds.Dy())
+	fmt.Fprintf(&buf, `  <image href="%s" xlink:href="%s" x="%d" y="%d" width="%d" height="%d" opacity="%s" preserveAspectRatio="none"/>`+"\n",
+		href.String(), href.String(), left, top, width, height, strconv.FormatFloat(opacity, 'g', -1, 64))
+	buf.WriteString("</svg>\n")
+	return buf.Bytes(), nil
+}
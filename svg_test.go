@@ -0,0 +1,47 @@
+package watermark
+
+import (
+	"encoding/xml"
+	"image"
+	"testing"
+)
+
+func TestSVGOverlay(t *testing.T) {
+	src, wm := image.Rect(0, 0, 640, 480), image.Rect(0, 0, 200, 80)
+	opts := Options{Position: BottomRight, Opacity: 0.4, Scale: 0.25, PaddingX: 12, PaddingY: 8}
+
+	data, err := SVGOverlay(src, wm, "logo.png?a=1&b=2", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Width  int `xml:"width,attr"`
+		Height int `xml:"height,attr"`
+		Image  struct {
+			Href    string  `xml:"href,attr"`
+			X       int     `xml:"x,attr"`
+			Y       int     `xml:"y,attr"`
+			Width   int     `xml:"width,attr"`
+			Height  int     `xml:"height,attr"`
+			Opacity float64 `xml:"opacity,attr"`
+		} `xml:"image"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid SVG: %v\n%s", err, data)
+	}
+
+	if doc.Width != 640 || doc.Height != 480 {
+		t.Errorf("document %dx%d, want 640x480", doc.Width, doc.Height)
+	}
+	r := opts.Resolve(src, wm)
+	img := doc.Image
+	if got := image.Rect(img.X, img.Y, img.X+img.Width, img.Y+img.Height); got != r.Rect {
+		t.Errorf("image box %v, want %v", got, r.Rect)
+	}
+	if img.Opacity != r.Opacity {
+		t.Errorf("opacity %v, want %v", img.Opacity, r.Opacity)
+	}
+	if img.Href != "logo.png?a=1&b=2" {
+		t.Errorf("href %q, want it escaped and intact", img.Href)
+	}
+}
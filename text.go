@@ -0,0 +1,193 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// TextOptions configures how text is rendered.
+type TextOptions struct {
+	// Face is the font face to render with. Nil uses basicfont.Face7x13.
+	Face font.Face
+	// Fallback lists faces tried in order for runes Face has no glyph for,
+	// e.g. a CJK or emoji face behind a Latin one. Runes no face covers are
+	// drawn with Face.
+	Fallback []font.Face
+	// Color is the text color. Nil uses white.
+	Color color.Color
+}
+
+// face returns the configured face or the default.
+func (t TextOptions) face() font.Face {
+	if t.Face == nil {
+		return basicfont.Face7x13
+	}
+	return t.Face
+}
+
+// faces returns the primary face followed by the non-nil fallback faces.
+func (t TextOptions) faces() []font.Face {
+	faces := []font.Face{t.face()}
+	for _, f := range t.Fallback {
+		if f != nil {
+			faces = append(faces, f)
+		}
+	}
+	return faces
+}
+
+// faceIndex returns the index of the first face in faces with a glyph for
+// r, or 0 (the primary face) if none has one.
+func faceIndex(faces []font.Face, r rune) int {
+	if len(faces) == 1 {
+		return 0
+	}
+	for i, f := range faces {
+		if hasGlyph(f, r) {
+			return i
+		}
+	}
+	return 0
+}
+
+// ascent returns the largest ascent of the primary and fallback faces, the
+// baseline position in images from renderText.
+func (t TextOptions) ascent() fixed.Int26_6 {
+	a, _ := t.extents()
+	return a
+}
+
+// extents returns the largest ascent and descent of the primary and
+// fallback faces.
+func (t TextOptions) extents() (ascent, descent fixed.Int26_6) {
+	for _, f := range t.faces() {
+		m := f.Metrics()
+		if m.Ascent > ascent {
+			ascent = m.Ascent
+		}
+		if m.Descent > descent {
+			descent = m.Descent
+		}
+	}
+	return ascent, descent
+}
+
+// hasGlyph reports whether face has a glyph for r. basicfont faces report
+// every rune as present and substitute U+FFFD, so their ranges are checked
+// directly.
+func hasGlyph(face font.Face, r rune) bool {
+	if bf, ok := face.(*basicfont.Face); ok {
+		for _, rng := range bf.Ranges {
+			if rng.Low <= r && r < rng.High {
+				return true
+			}
+		}
+		return false
+	}
+	_, ok := face.GlyphAdvance(r)
+	return ok
+}
+
+// color returns the configured color or the default.
+func (t TextOptions) color() color.Color {
+	if t.Color == nil {
+		return color.White
+	}
+	return t.Color
+}
+
+// renderText draws text onto a transparent image sized to fit it exactly:
+// as wide as its advance and as tall as the largest ascent plus descent of
+// the faces in topts, with the baseline at that ascent. Each rune is drawn
+// with the first face that has a glyph for it. Bidirectional text is
+// reordered for display first, so Arabic and Hebrew runs read right to left.
+func renderText(text string, topts TextOptions) *image.RGBA {
+	text = visualOrder(text)
+	ascent, descent := topts.extents()
+
+	// Split the text into runs sharing a face so kerning applies within each.
+	type run struct {
+		face int
+		text string
+	}
+	faces := topts.faces()
+	var runs []run
+	for _, r := range text {
+		i := faceIndex(faces, r)
+		if n := len(runs); n > 0 && runs[n-1].face == i {
+			runs[n-1].text += string(r)
+			continue
+		}
+		runs = append(runs, run{i, string(r)})
+	}
+
+	var w fixed.Int26_6
+	for _, r := range runs {
+		w += font.MeasureString(faces[r.face], r.text)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, w.Ceil(), (ascent + descent).Ceil()))
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(topts.color()), Dot: fixed.Point26_6{Y: ascent}}
+	for _, r := range runs {
+		d.Face = faces[r.face]
+		d.DrawString(r.text)
+	}
+	return img
+}
+
+// xHeight returns the height in pixels of a lowercase "x" above the
+// baseline in face, measured from the rendered glyph since many faces
+// report an approximate XHeight metric.
+func xHeight(face font.Face) int {
+	dr, mask, maskp, _, ok := face.Glyph(fixed.Point26_6{}, 'x')
+	if ok {
+		for y := dr.Min.Y; y < dr.Max.Y; y++ {
+			for x := dr.Min.X; x < dr.Max.X; x++ {
+				mx, my := maskp.X+x-dr.Min.X, maskp.Y+y-dr.Min.Y
+				if _, _, _, a := mask.At(mx, my).RGBA(); a > 0 {
+					return -y
+				}
+			}
+		}
+	}
+	return face.Metrics().XHeight.Ceil()
+}
+
+// drawOver composites img over dst with its top-left corner at pt.
+func drawOver(dst draw.Image, img image.Image, pt image.Point) {
+	b := img.Bounds()
+	draw.Draw(dst, image.Rectangle{Min: pt, Max: pt.Add(b.Size())}, img, b.Min, draw.Over)
+}
+
+// visualOrder reorders text from logical to visual order using the Unicode
+// bidirectional algorithm, reversing right-to-left runs so they can be drawn
+// left to right. Contextual glyph joining is left to the font; faces
+// without Arabic presentation forms render isolated letter forms. Text the
+// algorithm rejects is returned unchanged.
+func visualOrder(text string) string {
+	var p bidi.Paragraph
+	if _, err := p.SetString(text); err != nil {
+		return text
+	}
+	order, err := p.Order()
+	if err != nil {
+		return text
+	}
+
+	var out []byte
+	for i := 0; i < order.NumRuns(); i++ {
+		run := order.Run(i)
+		if run.Direction() == bidi.RightToLeft {
+			out = bidi.AppendReverse(out, run.Bytes())
+		} else {
+			out = append(out, run.Bytes()...)
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,68 @@
+package watermark
+
+import (
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"latin", "Hello", "Hello"},
+		// "salam" reads right to left, so its letters are drawn reversed.
+		{"arabic", "سلام", "مالس"},
+		{"hebrew", "שלום", "םולש"},
+		{"mixed", "by שלום", "by םולש"},
+	}
+	for _, tt := range tests {
+		if got := visualOrder(tt.in); got != tt.want {
+			t.Errorf("%s: visualOrder(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTextFallback(t *testing.T) {
+	// A stand-in CJK face that draws the basicfont "#" for 中 and 文, which
+	// basicfont itself has no glyphs for.
+	cjk := *basicfont.Face7x13
+	cjk.Ranges = []basicfont.Range{{Low: '中', High: '中' + 1, Offset: '#' - ' '}, {Low: '文', High: '文' + 1, Offset: '#' - ' '}}
+
+	got := renderText("ab中文", TextOptions{Fallback: []font.Face{&cjk}})
+	want := renderText("ab##", TextOptions{})
+	if got.Bounds() != want.Bounds() {
+		t.Fatalf("bounds = %v, want %v", got.Bounds(), want.Bounds())
+	}
+	if r := changed(got, want); !r.Empty() {
+		t.Errorf("fallback glyphs differ from the CJK face's in %v", r)
+	}
+
+	// Without the fallback the primary face draws its replacement glyph.
+	if r := changed(renderText("ab中文", TextOptions{}), want); r.Empty() {
+		t.Error("text rendered without a fallback used the CJK face's glyphs")
+	}
+}
+
+func TestRenderTextRTL(t *testing.T) {
+	// A stand-in Arabic face that draws the basicfont Latin letters s, l, a
+	// and m for س, ل, ا and م, so glyph positions can be checked against
+	// Latin renderings.
+	arabic := *basicfont.Face7x13
+	arabic.Ranges = nil
+	for _, m := range []struct{ ar, latin rune }{{'س', 's'}, {'ل', 'l'}, {'ا', 'a'}, {'م', 'm'}} {
+		arabic.Ranges = append(arabic.Ranges, basicfont.Range{Low: m.ar, High: m.ar + 1, Offset: int(m.latin - ' ')})
+	}
+	topts := TextOptions{Face: &arabic}
+
+	// "salam" is stored s-l-a-m but reads right to left, so m is drawn in
+	// the leftmost column and s in the rightmost.
+	got := renderText("سلام", topts)
+	if r := changed(got, renderText("mals", TextOptions{})); !r.Empty() {
+		t.Errorf("glyphs differ from right-to-left order in %v", r)
+	}
+	if naive := renderText("slam", TextOptions{}); changed(got, naive).Empty() {
+		t.Error("Arabic text was drawn in logical left-to-right order")
+	}
+}
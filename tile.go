@@ -0,0 +1,111 @@
+package watermark
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"math/rand"
+)
+
+// TileEdge controls how tiled stamps that do not fully fit are handled.
+type TileEdge int
+
+const (
+	// ClipEdges draws overflowing stamps, clipping them at the source edge.
+	ClipEdges TileEdge = iota
+	// SkipPartial leaves out any stamp that does not fully fit, so the
+	// edges of the source stay clean.
+	SkipPartial
+)
+
+// TileWithOptions applies a watermark in a tiled pattern across the image,
+// honouring the scaling, opacity, spacing and edge handling in opts.
+func TileWithOptions(src, watermark image.Image, opts Options) image.Image {
+	srcBounds := src.Bounds()
+	r := opts.Resolve(srcBounds, watermark.Bounds())
+	watermark = prepareWatermark(watermark, r.Rect.Size(), opts)
+
+	dst := newCanvas(src)
+	tile(dst, watermark, r.Opacity, opts)
+	return dst
+}
+
+// tile stamps watermark across dst in a grid with the given opacity.
+func tile(dst draw.Image, watermark image.Image, opacity float64, opts Options) {
+	bounds := dst.Bounds()
+	wmSize := watermark.Bounds().Size()
+
+	stepX := wmSize.X + opts.SpacingX
+	stepY := wmSize.Y + opts.SpacingY
+	if stepX < 1 || stepY < 1 {
+		return
+	}
+
+	cols := tileCount(bounds.Dx(), stepX, opts.RepeatX)
+	rows := tileCount(bounds.Dy(), stepY, opts.RepeatY)
+	origin := bounds.Min
+	if opts.TileCenter {
+		if opts.TileEdge == SkipPartial {
+			cols = tileFit(cols, bounds.Dx(), stepX, opts.SpacingX)
+			rows = tileFit(rows, bounds.Dy(), stepY, opts.SpacingY)
+		}
+		origin = origin.Add(image.Pt(
+			halfFloor(bounds.Dx()-(cols*stepX-opts.SpacingX)),
+			halfFloor(bounds.Dy()-(rows*stepY-opts.SpacingY)),
+		))
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if opts.TileDensity > 0 && opts.TileDensity < 1 && rng.Float64() >= opts.TileDensity {
+				continue
+			}
+			pt := origin.Add(image.Pt(col*stepX, row*stepY))
+			if opts.Jitter > 0 {
+				pt = pt.Add(image.Pt(rng.Intn(2*opts.Jitter+1)-opts.Jitter, rng.Intn(2*opts.Jitter+1)-opts.Jitter))
+			}
+			if opts.TileEdge == SkipPartial && !(image.Rectangle{Min: pt, Max: pt.Add(wmSize)}).In(bounds) {
+				continue
+			}
+			o := opts.opacityBand(opacity * falloff(pt.Add(wmSize.Div(2)).Sub(bounds.Min), opts))
+			stamp(dst, watermark, pt, o, opts.BlendMode)
+		}
+	}
+}
+
+// tileCount returns how many stamps spaced step apart start within length,
+// capped at repeat when it is positive.
+func tileCount(length, step, repeat int) int {
+	n := (length + step - 1) / step
+	if repeat > 0 && repeat < n {
+		n = repeat
+	}
+	return n
+}
+
+// tileFit caps n at the number of stamps spaced step apart, with spacing
+// between them, that fit entirely within length.
+func tileFit(n, length, step, spacing int) int {
+	if fit := (length + spacing) / step; fit < n {
+		return fit
+	}
+	return n
+}
+
+// falloff returns the opacity multiplier for a stamp centered at p, relative
+// to the source origin, according to the radial falloff in opts.
+func falloff(p image.Point, opts Options) float64 {
+	if opts.FalloffRadius <= 0 {
+		return 1
+	}
+	d := p.Sub(opts.FalloffCenter)
+	f := 1 - math.Hypot(float64(d.X), float64(d.Y))/opts.FalloffRadius
+	if f < 0 {
+		f = 0
+	}
+	if opts.FalloffInvert {
+		f = 1 - f
+	}
+	return f
+}
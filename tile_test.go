@@ -0,0 +1,220 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestTileSkipPartial(t *testing.T) {
+	src := fill(100, 100, color.Black)
+	wm := fill(30, 30, color.White)
+
+	// With a step of 40, stamps start at 0, 40 and 80; the last column and
+	// row overflow the 100px source.
+	opts := Options{Opacity: 1, SpacingX: 10, SpacingY: 10}
+	clipped := TileWithOptions(src, wm, opts)
+	if c := rgbaAt(clipped, 90, 10); c.R != 255 {
+		t.Errorf("ClipEdges: pixel in overflowing stamp = %v, want white", c)
+	}
+
+	opts.TileEdge = SkipPartial
+	skipped := TileWithOptions(src, wm, opts)
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			white := x%40 < 30 && y%40 < 30 && x < 80 && y < 80
+			if got := rgbaAt(skipped, x, y).R == 255; got != white {
+				t.Fatalf("SkipPartial: pixel (%d, %d) white = %v, want %v", x, y, got, white)
+			}
+		}
+	}
+}
+
+func TestTileStampsGrid(t *testing.T) {
+	src := fill(90, 90, color.Black)
+	out := Tile(src, fill(20, 20, color.White), 1, 10)
+
+	// Stamps start every 30px, so every third 10px band is a gap.
+	for _, p := range []image.Point{{5, 5}, {35, 5}, {65, 65}} {
+		if c := rgbaAt(out, p.X, p.Y); c.R != 255 {
+			t.Errorf("stamp pixel %v = %v, want white", p, c)
+		}
+	}
+	for _, p := range []image.Point{{25, 5}, {5, 25}, {55, 85}} {
+		if c := rgbaAt(out, p.X, p.Y); c.R != 0 {
+			t.Errorf("gap pixel %v = %v, want black", p, c)
+		}
+	}
+}
+
+func TestTileFalloff(t *testing.T) {
+	src := fill(200, 200, color.Black)
+	wm := fill(20, 20, color.White)
+	opts := Options{
+		Opacity:       1,
+		SpacingX:      20,
+		SpacingY:      20,
+		FalloffCenter: image.Pt(100, 100),
+		FalloffRadius: 150,
+	}
+
+	// Stamps start every 40px; (80, 80) holds the stamp nearest the center
+	// and (0, 0) a corner one.
+	out := TileWithOptions(src, wm, opts)
+	center, corner := rgbaAt(out, 85, 85).R, rgbaAt(out, 5, 5).R
+	if center <= corner {
+		t.Errorf("center stamp %d not brighter than corner stamp %d", center, corner)
+	}
+
+	opts.FalloffInvert = true
+	out = TileWithOptions(src, wm, opts)
+	center, corner = rgbaAt(out, 85, 85).R, rgbaAt(out, 5, 5).R
+	if center >= corner {
+		t.Errorf("inverted: center stamp %d not dimmer than corner stamp %d", center, corner)
+	}
+}
+
+func TestTileSeed(t *testing.T) {
+	src := fill(200, 200, color.Black)
+	wm := fill(20, 20, color.White)
+	opts := Options{Opacity: 1, SpacingX: 20, SpacingY: 20, Jitter: 8, Seed: 42}
+
+	a := TileWithOptions(src, wm, opts)
+	b := TileWithOptions(src, wm, opts)
+	if r := changed(a, b); !r.Empty() {
+		t.Errorf("same Seed: outputs differ in %v", r)
+	}
+
+	opts.Seed = 43
+	if c := TileWithOptions(src, wm, opts); changed(a, c).Empty() {
+		t.Error("different Seed: outputs are identical")
+	}
+}
+
+func TestTileRepeat(t *testing.T) {
+	src := fill(300, 200, color.Black)
+	out := TileWithOptions(src, fill(20, 20, color.White), Options{Opacity: 1, SpacingX: 30, SpacingY: 10, RepeatX: 3, RepeatY: 1})
+
+	// Count the stamps along the first row by their left edges.
+	stamps := 0
+	for x := 0; x < 300; x++ {
+		if rgbaAt(out, x, 10).R == 255 && (x == 0 || rgbaAt(out, x-1, 10).R == 0) {
+			stamps++
+		}
+	}
+	if stamps != 3 {
+		t.Errorf("stamps in the first row = %d, want 3", stamps)
+	}
+	if r := changed(src, out); r != image.Rect(0, 0, 120, 20) {
+		t.Errorf("changed %v, want a single row of three stamps", r)
+	}
+}
+
+func TestTileDensity(t *testing.T) {
+	src := fill(200, 200, color.Black)
+	wm := fill(10, 10, color.White)
+	opts := Options{Opacity: 1, SpacingX: 10, SpacingY: 10, TileDensity: 0.5, Seed: 7}
+
+	// stamped lists which of the 10x10 grid cells received a stamp.
+	stamped := func(img image.Image) []bool {
+		cells := make([]bool, 100)
+		for i := range cells {
+			cells[i] = rgbaAt(img, i%10*20+5, i/10*20+5).R == 255
+		}
+		return cells
+	}
+
+	first := stamped(TileWithOptions(src, wm, opts))
+	n := 0
+	for _, s := range first {
+		if s {
+			n++
+		}
+	}
+	if n < 30 || n > 70 {
+		t.Errorf("%d of 100 cells stamped, want about half", n)
+	}
+
+	second := stamped(TileWithOptions(src, wm, opts))
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("cell %d stamped = %v, then %v: want the same subset", i, first[i], second[i])
+		}
+	}
+}
+
+func TestTileOpacityBand(t *testing.T) {
+	src := fill(200, 200, color.Black)
+	wm := fill(20, 20, color.White)
+	opts := Options{
+		Opacity:       1,
+		SpacingX:      20,
+		SpacingY:      20,
+		FalloffCenter: image.Pt(100, 100),
+		FalloffRadius: 150,
+	}
+
+	// White over black shows each pixel's effective opacity as its level.
+	levels := func(img image.Image) (lo, hi uint8) {
+		lo = 255
+		for y := 0; y < 200; y++ {
+			for x := 0; x < 200; x++ {
+				if (x/20)%2 == 1 || (y/20)%2 == 1 {
+					continue // gap between stamps
+				}
+				v := rgbaAt(img, x, y).R
+				if v < lo {
+					lo = v
+				}
+				if v > hi {
+					hi = v
+				}
+			}
+		}
+		return lo, hi
+	}
+
+	if lo, hi := levels(TileWithOptions(src, wm, opts)); lo > 51 || hi < 204 {
+		t.Fatalf("unbanded falloff spans %d-%d, want beyond 51-204", lo, hi)
+	}
+	opts.OpacityMin, opts.OpacityMax = 0.2, 0.8
+	if lo, hi := levels(TileWithOptions(src, wm, opts)); lo < 51 || hi > 204 {
+		t.Errorf("banded falloff spans %d-%d, want within 51-204", lo, hi)
+	}
+}
+
+func TestTileCenter(t *testing.T) {
+	wm := fill(20, 20, color.White)
+	for _, edge := range []TileEdge{SkipPartial, ClipEdges} {
+		for _, w := range []int{230, 245, 263} {
+			src := fill(w, 200, color.Black)
+			opts := Options{Opacity: 1, SpacingX: 20, SpacingY: 20, TileEdge: edge, TileCenter: true}
+			out := TileWithOptions(src, wm, opts)
+
+			// A centered grid is symmetric across the middle of the source,
+			// to within the odd pixel of leftover space.
+			symmetric := func(shift int) bool {
+				for x := 0; x < w; x++ {
+					m := w - 1 - x + shift
+					if m < 0 || m >= w {
+						continue
+					}
+					if rgbaAt(out, x, 10) != rgbaAt(out, m, 10) {
+						return false
+					}
+				}
+				return true
+			}
+			if !symmetric(0) && !symmetric(1) && !symmetric(-1) {
+				t.Errorf("edge %d, width %d: grid is not centered", edge, w)
+			}
+
+			if edge == SkipPartial {
+				r := colorBounds(out, func(c color.RGBA) bool { return c.R == 255 })
+				if left, right := r.Min.X, w-r.Max.X; left < 0 || right < 0 || left-right > 1 || right-left > 1 {
+					t.Errorf("width %d: left margin %d, right margin %d", w, left, right)
+				}
+			}
+		}
+	}
+}
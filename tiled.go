@@ -0,0 +1,62 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+)
+
+// ApplyTiled is like Apply but processes src in square tiles of tileSize
+// pixels, to bound the memory used beyond the output for very large images.
+// Placement is resolved once against the whole source and the watermark is
+// stamped into each tile it touches, clipped to that tile, so the result
+// matches Apply pixel for pixel even when the watermark straddles a tile
+// seam. A BackdropBlur reads across tiles, so its footprint is composited
+// once in a buffer of its own. A tileSize of zero or less processes the
+// image in one pass.
+func ApplyTiled(src, watermark image.Image, opts Options, tileSize int) image.Image {
+	b := src.Bounds()
+	if tileSize <= 0 || (tileSize >= b.Dx() && tileSize >= b.Dy()) {
+		return Apply(src, watermark, opts)
+	}
+
+	gray := keepsGray(src, watermark, opts)
+	if opts.AutoComplementTint {
+		view := canvasView{src, blankCanvas(src, image.Rectangle{}, gray).ColorModel()}
+		watermark = tint(watermark, complementColor(dominantColor(view)))
+		opts.AutoComplementTint = false
+	}
+	r := opts.Resolve(b, watermark.Bounds())
+	watermark = prepareWatermark(watermark, r.Rect.Size(), opts)
+
+	dst := blankCanvas(src, b, gray)
+	for y := b.Min.Y; y < b.Max.Y; y += tileSize {
+		for x := b.Min.X; x < b.Max.X; x += tileSize {
+			t := image.Rect(x, y, x+tileSize, y+tileSize).Intersect(b)
+			copyCanvas(dst, src, t)
+			if opts.BackdropBlur <= 0 && t.Overlaps(r.Rect) {
+				composite(subCanvas(dst, t), watermark, r, opts)
+			}
+		}
+	}
+
+	if opts.BackdropBlur > 0 {
+		footprint := r.Rect.Inset(-opts.BackdropBlur).Intersect(b)
+		if !footprint.Empty() {
+			work := cropCanvas(src, footprint, gray)
+			composite(work, watermark, r, opts)
+			copyCanvas(dst, work, footprint)
+		}
+	}
+	return dst
+}
+
+// canvasView presents an image as newCanvas would copy it, converting each
+// pixel on read instead of allocating a full-size buffer.
+type canvasView struct {
+	image.Image
+	model color.Model
+}
+
+func (v canvasView) ColorModel() color.Model { return v.model }
+
+func (v canvasView) At(x, y int) color.Color { return v.model.Convert(v.Image.At(x, y)) }
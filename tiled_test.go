@@ -0,0 +1,56 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyTiled(t *testing.T) {
+	src := photo(200, 150)
+	src16 := image.NewRGBA64(src.Bounds())
+	for y := 0; y < 150; y++ {
+		for x := 0; x < 200; x++ {
+			src16.Set(x, y, src.At(x, y))
+		}
+	}
+	wm := photo(50, 30)
+
+	// With 64px tiles, a watermark at (40, 50) straddles the seams at x=64
+	// and y=64.
+	anchor := image.Pt(40, 50)
+	tests := []struct {
+		name string
+		src  image.Image
+		opts Options
+	}{
+		{"seam", src, Options{Anchor: &anchor, Opacity: 0.6}},
+		{"16-bit", src16, Options{Anchor: &anchor, Opacity: 0.6}},
+		{"contrast", src, Options{Anchor: &anchor, BlendMode: BlendContrast}},
+		{"backdrop blur", src, Options{Anchor: &anchor, BackdropBlur: 4}},
+		{"backdrop hue", src, Options{Anchor: &anchor, BackdropHue: 90}},
+		{"clipped corner", src, Options{Position: BottomRight, PaddingX: -20, PaddingY: -10}},
+	}
+	for _, tt := range tests {
+		want := Apply(tt.src, wm, tt.opts)
+		got := ApplyTiled(tt.src, wm, tt.opts, 64)
+		if got.Bounds() != want.Bounds() {
+			t.Errorf("%s: bounds %v, want %v", tt.name, got.Bounds(), want.Bounds())
+			continue
+		}
+		if r := changed(got, want); !r.Empty() {
+			t.Errorf("%s: tiled output differs from Apply in %v", tt.name, r)
+		}
+		if _, ok := got.(*image.RGBA64); ok != is16Bit(tt.src) {
+			t.Errorf("%s: output %T for a %T source", tt.name, got, tt.src)
+		}
+	}
+}
+
+func TestApplyTiledUntouched(t *testing.T) {
+	src := photo(200, 150)
+	out := ApplyTiled(src, fill(20, 20, color.White), Options{Position: TopLeft, Opacity: 1}, 64)
+	if r := changed(src, out); r != image.Rect(0, 0, 20, 20) {
+		t.Errorf("changed region %v, want only the watermark", r)
+	}
+}
@@ -0,0 +1,33 @@
+package watermark
+
+import "image"
+
+// ValidateWatermark checks that img is suitable as a watermark before it is
+// accepted, e.g. from a tenant upload. It returns ErrWatermarkTooLarge if
+// either side exceeds maxDim (when maxDim is positive) and
+// ErrNoTransparency if fewer than minAlphaFraction of its pixels are at
+// least partly transparent.
+func ValidateWatermark(img image.Image, minAlphaFraction float64, maxDim int) error {
+	b := img.Bounds()
+	if maxDim > 0 && (b.Dx() > maxDim || b.Dy() > maxDim) {
+		return ErrWatermarkTooLarge
+	}
+
+	total := b.Dx() * b.Dy()
+	if total == 0 {
+		return ErrNoTransparency
+	}
+
+	var transparent int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a < 0xffff {
+				transparent++
+			}
+		}
+	}
+	if transparent == 0 || float64(transparent)/float64(total) < minAlphaFraction {
+		return ErrNoTransparency
+	}
+	return nil
+}
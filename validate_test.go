@@ -0,0 +1,35 @@
+package watermark
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestValidateWatermark(t *testing.T) {
+	// A logo with an opaque core in a transparent margin.
+	logo := func(size int) image.Image {
+		img := image.NewNRGBA(image.Rect(0, 0, size, size))
+		draw.Draw(img, image.Rect(size/4, size/4, size*3/4, size*3/4), image.NewUniform(color.White), image.Point{}, draw.Src)
+		return img
+	}
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		minAlpha float64
+		want     error
+	}{
+		{"opaque", fill(32, 32, color.White), 0, ErrNoTransparency},
+		{"small transparent", logo(16), 0.5, nil},
+		{"oversized", logo(2048), 0.5, ErrWatermarkTooLarge},
+		// Three quarters of the logo is transparent margin.
+		{"below the fraction", logo(32), 0.8, ErrNoTransparency},
+	}
+	for _, tt := range tests {
+		if err := ValidateWatermark(tt.img, tt.minAlpha, 1024); err != tt.want {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package watermark
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// WalkImages walks the tree rooted at root and calls fn for each supported
+// image file as it is found, without collecting paths first, so directories
+// with millions of files can be processed in constant memory. Files are
+// recognised by extension. An error returned by fn stops the walk.
+func WalkImages(root string, fn func(path string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isSupportedImage(path) {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// isSupportedImage reports whether path has an extension this package can
+// decode and encode.
+func isSupportedImage(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
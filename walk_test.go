@@ -0,0 +1,62 @@
+package watermark
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestWalkImages(t *testing.T) {
+	root := t.TempDir()
+	files := []string{"a.png", "b.JPG", "notes.txt", "sub/c.jpeg", "sub/d.gif", "sub/deeper/e.png"}
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	err := WalkImages(root, func(path string) error {
+		rel, _ := filepath.Rel(root, path)
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	want := []string{"a.png", "b.JPG", "sub/c.jpeg", "sub/deeper/e.png"}
+	if len(got) != len(want) {
+		t.Fatalf("visited %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("visited %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWalkImagesStops(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"a.png", "b.png"} {
+		if err := os.WriteFile(filepath.Join(root, f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := WalkImages(root, func(string) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("error = %v after %d calls, want stop after 1", err, calls)
+	}
+}
@@ -2,20 +2,25 @@
 package watermark
 
 import (
+	"bytes"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"math"
+	"mime/multipart"
 	"os"
+	"sync"
 )
 
 // Position specifies where to place the watermark.
 type Position int
 
 const (
-	// Center places the watermark in the center.
+	// Center places the watermark in the center. When the leftover space is
+	// odd, the extra pixel goes to the right and bottom margins.
 	Center Position = iota
 	// TopLeft places the watermark in the top-left corner.
 	TopLeft
@@ -25,14 +30,205 @@ const (
 	BottomLeft
 	// BottomRight places the watermark in the bottom-right corner.
 	BottomRight
+	// GoldenTopLeft centers the watermark on the top-left golden-ratio
+	// point. See GoldenPoint.
+	GoldenTopLeft
+	// GoldenTopRight centers the watermark on the top-right golden-ratio
+	// point.
+	GoldenTopRight
+	// GoldenBottomLeft centers the watermark on the bottom-left golden-ratio
+	// point.
+	GoldenBottomLeft
+	// GoldenBottomRight centers the watermark on the bottom-right
+	// golden-ratio point.
+	GoldenBottomRight
+	// Polar centers the watermark at Options.Bearing and Options.Distance
+	// from the center of the image.
+	Polar
 )
 
+// ScaleBy selects the source dimension a fractional Scale is measured
+// against.
+type ScaleBy int
+
+const (
+	// Width measures Scale against the source width.
+	Width ScaleBy = iota
+	// Height measures Scale against the source height.
+	Height
+	// ShortEdge measures Scale against the shorter source dimension.
+	ShortEdge
+	// LongEdge measures Scale against the longer source dimension.
+	LongEdge
+	// Area measures Scale against the square root of the source area, so
+	// the watermark grows sub-linearly with megapixels: quadrupling the
+	// source area doubles the watermark width.
+	Area
+)
+
+// Rounding selects how fractional pixel sizes are rounded to whole pixels.
+type Rounding int
+
+const (
+	// RoundNearest rounds to the nearest pixel, halves away from zero.
+	RoundNearest Rounding = iota
+	// RoundFloor rounds down.
+	RoundFloor
+	// RoundCeil rounds up.
+	RoundCeil
+)
+
+// BlendMode selects how watermark pixels are combined with the source.
+type BlendMode int
+
+const (
+	// BlendNormal composites the watermark over the source.
+	BlendNormal BlendMode = iota
+	// BlendContrast inverts each watermark pixel whose lightness matches the
+	// source behind it, so the mark stays readable as light-on-dark over
+	// dark areas and dark-on-light over light ones.
+	BlendContrast
+	// BlendAdditive adds the watermark, scaled by its alpha and the
+	// opacity, to the source, clamping at white, for light-leak and glow
+	// effects on dark photos.
+	BlendAdditive
+)
+
+// Insets reserves space along each edge of the source, such as status bars
+// or notches, that the watermark must not be placed under.
+type Insets struct {
+	Top    int
+	Right  int
+	Bottom int
+	Left   int
+}
+
 // Options configures watermark placement.
 type Options struct {
 	Position Position
-	Opacity  float64 // 0.0 to 1.0
-	PaddingX int
-	PaddingY int
+	// Opacity scales the watermark's own alpha, from 0.0 to 1.0. Zero or
+	// negative values select the default of 0.5; values above 1 are clamped.
+	Opacity float64
+	// OpacityMin and OpacityMax clamp the resolved opacity, and the
+	// opacity computed for each stamp by adaptive features such as
+	// FalloffRadius, so the watermark never fully disappears or fully
+	// dominates. A zero OpacityMax means no ceiling. If OpacityMin exceeds
+	// OpacityMax, OpacityMax wins.
+	OpacityMin float64
+	OpacityMax float64
+	PaddingX   int
+	PaddingY   int
+	// RelPaddingX and RelPaddingY, when positive, set the padding as a
+	// fraction of the source width and height, overriding PaddingX and
+	// PaddingY so the margin keeps its proportion across image sizes.
+	RelPaddingX float64
+	RelPaddingY float64
+	// OffsetX and OffsetY nudge the final placement by a fixed delta after
+	// position and padding have been applied.
+	OffsetX int
+	OffsetY int
+	// Insets shrinks the region the watermark is positioned within before
+	// position and padding are applied.
+	Insets Insets
+	// Anchor, if set, places the watermark's top-left corner at this point
+	// relative to the source origin, overriding Position, padding and
+	// insets.
+	Anchor *image.Point
+	// Bearing is the compass direction of a Polar placement in degrees,
+	// clockwise from straight up: 90 is right and 180 is down.
+	Bearing float64
+	// Distance is how far from the image center a Polar placement lies, as
+	// a fraction of the half-diagonal: 0 is the center and 1 a corner.
+	Distance float64
+	// RTL mirrors left and right positions for right-to-left layouts, so
+	// BottomRight places the watermark bottom-left and so on.
+	RTL bool
+	// Feather blurs the watermark's alpha channel by this many pixels,
+	// softening its edges into the photo. Zero disables feathering.
+	Feather int
+	// AlphaThreshold treats watermark pixels with an alpha below this value
+	// (0-255) as fully transparent, removing faint halos left around
+	// cut-out logos with imperfect alpha.
+	AlphaThreshold uint8
+	// AutoComplementTint recolors the watermark to the complementary hue of
+	// the source's dominant color so it stands out. The watermark's alpha
+	// is kept; its own colors are replaced.
+	AutoComplementTint bool
+	// Desaturate converts the watermark to grayscale, keeping its alpha.
+	// Watermarks that are already gray are used as they are.
+	Desaturate bool
+	// BlendMode selects how watermark pixels are combined with the source.
+	BlendMode BlendMode
+	// BackdropBlur Gaussian-blurs the source behind the watermark by this
+	// many pixels before compositing, for a frosted-glass backdrop. The blur
+	// covers the watermark footprint grown by the same radius.
+	BackdropBlur int
+	// BackdropHue rotates the hue of the source under the watermark
+	// footprint by this many degrees before compositing, for a
+	// color-shifted stamp region. Zero leaves the source unchanged.
+	BackdropHue float64
+	// Scale sizes the watermark width as a fraction of the source dimension
+	// selected by ScaleBy, preserving its aspect ratio. Zero keeps the
+	// watermark at its native size.
+	Scale   float64
+	ScaleBy ScaleBy
+	// ScaleRounding rounds the scaled watermark size to whole pixels. The
+	// default rounds to nearest.
+	ScaleRounding Rounding
+	// MinWatermarkPx keeps the smaller side of a scaled watermark at or
+	// above this many pixels, even if that exceeds Scale, the fixed box or
+	// the MaxWidth and MaxHeight box.
+	MinWatermarkPx int
+	// BoxWidth and BoxHeight, when both positive, define a fixed-size slot
+	// that is positioned instead of the watermark itself. The watermark is
+	// scaled to fit inside the slot without distortion and centered in it,
+	// overriding Scale.
+	BoxWidth  int
+	BoxHeight int
+	// MaxWidth and MaxHeight, when either is positive, scale the watermark
+	// to the largest size that fits within a box of those fractions of the
+	// source width and height without distortion, so whichever side is
+	// limiting wins. A zero side is unconstrained. They override Scale.
+	MaxWidth  float64
+	MaxHeight float64
+	// SpacingX and SpacingY are the horizontal and vertical gaps in pixels
+	// between stamps when tiling.
+	SpacingX int
+	SpacingY int
+	// RepeatX and RepeatY limit tiling to that many stamps per row and per
+	// column. Zero fills the source along that axis.
+	RepeatX int
+	RepeatY int
+	// TileEdge controls how tiled stamps that overflow the right and bottom
+	// edges of the source are handled.
+	TileEdge TileEdge
+	// TileCenter centers the tile grid on the source, splitting the space
+	// left over, or the overhang of clipped stamps, evenly between
+	// opposite edges instead of anchoring the grid at the top-left corner.
+	TileCenter bool
+	// FalloffRadius, when positive, fades tiled stamps with the distance of
+	// their center from FalloffCenter (relative to the source origin): full
+	// opacity at the center, none at the radius and beyond. FalloffInvert
+	// reverses the ramp so stamps strengthen towards the edges instead.
+	FalloffCenter image.Point
+	FalloffRadius float64
+	FalloffInvert bool
+	// Jitter randomly displaces each tiled stamp by up to this many pixels
+	// along each axis.
+	Jitter int
+	// TileDensity is the probability, between 0 and 1, that each grid cell
+	// receives a stamp when tiling, for a scattered look. Zero stamps every
+	// cell.
+	TileDensity float64
+	// Seed seeds every randomized feature, so the same Options always
+	// produce the same output.
+	Seed int64
+	// OutputDPI is the resolution declared in files written by the
+	// helpers that save to a path. Zero declares none.
+	OutputDPI int
+	// Copyright is written as the EXIF copyright notice by
+	// WatermarkFilePreservingEXIF. Empty keeps the source's notice.
+	Copyright string
 }
 
 // DefaultOptions returns sensible watermark defaults.
@@ -45,83 +241,325 @@ func DefaultOptions() Options {
 	}
 }
 
-// Apply applies a watermark image to the source image.
+// Apply applies a watermark image to the source image. A grayscale source
+// stamped with a grayscale watermark stays grayscale.
 func Apply(src, watermark image.Image, opts Options) image.Image {
-	srcBounds := src.Bounds()
+	dst := cropCanvas(src, src.Bounds(), keepsGray(src, watermark, opts))
+	applyTo(dst, watermark, opts)
+	return dst
+}
+
+// ApplyRGBA is like Apply but always composites into, and returns, an
+// *image.RGBA, so callers can keep drawing on the result without a type
+// assertion. Unlike Apply, 16-bit sources are reduced to 8 bits.
+func ApplyRGBA(src, watermark image.Image, opts Options) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, src, b.Min, draw.Src)
+	applyTo(dst, watermark, opts)
+	return dst
+}
+
+// applyTo resolves opts against dst and composites the watermark onto it in
+// place, including any backdrop effects.
+func applyTo(dst draw.Image, watermark image.Image, opts Options) {
+	if opts.AutoComplementTint {
+		watermark = tint(watermark, complementColor(dominantColor(dst)))
+	}
+
+	r := opts.Resolve(dst.Bounds(), watermark.Bounds())
+	watermark = prepareWatermark(watermark, r.Rect.Size(), opts)
+	composite(dst, watermark, r, opts)
+}
+
+// composite applies the backdrop effects requested by opts around r.Rect
+// and stamps the prepared watermark there.
+func composite(dst draw.Image, watermark image.Image, r ResolvedOptions, opts Options) {
+	if opts.BackdropBlur > 0 {
+		blurRegion(dst, r.Rect.Inset(-opts.BackdropBlur), opts.BackdropBlur)
+	}
+	if opts.BackdropHue != 0 {
+		hueRotateRegion(dst, r.Rect, opts.BackdropHue)
+	}
+	stamp(dst, watermark, r.Rect.Min, r.Opacity, opts.BlendMode)
+}
+
+// opacityBand clamps an opacity into the [OpacityMin, OpacityMax] band.
+func (o Options) opacityBand(v float64) float64 {
+	if v < o.OpacityMin {
+		v = o.OpacityMin
+	}
+	if o.OpacityMax > 0 && v > o.OpacityMax {
+		v = o.OpacityMax
+	}
+	return v
+}
+
+// prepareWatermark resizes watermark to size if needed and applies any
+// per-watermark effects requested by opts.
+func prepareWatermark(watermark image.Image, size image.Point, opts Options) image.Image {
+	if opts.Desaturate && !isGray(watermark) {
+		watermark = desaturate(watermark)
+	}
+	if opts.AlphaThreshold > 0 {
+		watermark = thresholdAlpha(watermark, opts.AlphaThreshold)
+	}
+	if size != watermark.Bounds().Size() {
+		watermark = resize(watermark, size.X, size.Y)
+	}
+	if opts.Feather > 0 {
+		watermark = featherAlpha(watermark, opts.Feather)
+	}
+	return watermark
+}
+
+// thresholdAlpha returns a copy of img in which pixels with an alpha below
+// threshold are fully transparent.
+func thresholdAlpha(img image.Image, threshold uint8) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	for i := 0; i < len(dst.Pix); i += 4 {
+		if dst.Pix[i+3] < threshold {
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = 0, 0, 0, 0
+		}
+	}
+	return dst
+}
+
+// stamp composites watermark onto dst with its top-left corner at pt,
+// clipped to the bounds of dst. 8-bit canvases in BlendNormal mode take a
+// fast path through draw.DrawMask with a uniform opacity mask.
+func stamp(dst draw.Image, watermark image.Image, pt image.Point, opacity float64, mode BlendMode) {
 	wmBounds := watermark.Bounds()
+	area := image.Rectangle{Min: pt, Max: pt.Add(wmBounds.Size())}.Intersect(dst.Bounds())
+
+	if rgba, ok := dst.(*image.RGBA); ok && mode == BlendNormal {
+		sp := wmBounds.Min.Add(area.Min.Sub(pt))
+		draw.DrawMask(rgba, area, watermark, sp, opacityMask(opacity), image.Point{}, draw.Over)
+		return
+	}
 
-	dst := image.NewRGBA(srcBounds)
-	draw.Draw(dst, srcBounds, src, srcBounds.Min, draw.Src)
-
-	// Calculate watermark position
-	var x, y int
-	switch opts.Position {
-	case Center:
-		x = (srcBounds.Dx() - wmBounds.Dx()) / 2
-		y = (srcBounds.Dy() - wmBounds.Dy()) / 2
-	case TopLeft:
-		x = opts.PaddingX
-		y = opts.PaddingY
-	case TopRight:
-		x = srcBounds.Dx() - wmBounds.Dx() - opts.PaddingX
-		y = opts.PaddingY
-	case BottomLeft:
-		x = opts.PaddingX
-		y = srcBounds.Dy() - wmBounds.Dy() - opts.PaddingY
-	case BottomRight:
-		x = srcBounds.Dx() - wmBounds.Dx() - opts.PaddingX
-		y = srcBounds.Dy() - wmBounds.Dy() - opts.PaddingY
-	}
-
-	// Apply watermark with opacity
-	if opts.Opacity <= 0 {
-		opts.Opacity = 0.5
-	}
-	if opts.Opacity > 1 {
-		opts.Opacity = 1
-	}
-
-	for wy := 0; wy < wmBounds.Dy(); wy++ {
-		for wx := 0; wx < wmBounds.Dx(); wx++ {
-			dx := x + wx
-			dy := y + wy
-			if dx < 0 || dx >= srcBounds.Dx() || dy < 0 || dy >= srcBounds.Dy() {
+	blend := blenderFor(dst, mode)
+	for dy := area.Min.Y; dy < area.Max.Y; dy++ {
+		for dx := area.Min.X; dx < area.Max.X; dx++ {
+			wmColor := watermark.At(wmBounds.Min.X+dx-pt.X, wmBounds.Min.Y+dy-pt.Y)
+			if _, _, _, a := wmColor.RGBA(); a == 0 {
 				continue
 			}
 
 			srcColor := dst.At(dx, dy)
-			wmColor := watermark.At(wmBounds.Min.X+wx, wmBounds.Min.Y+wy)
-
-			blended := blendColors(srcColor, wmColor, opts.Opacity)
+			if mode == BlendContrast {
+				wmColor = contrastOverlay(srcColor, wmColor)
+			}
+			blended := blend(srcColor, wmColor, opacity)
 			dst.Set(dx, dy, blended)
 		}
 	}
+}
+
+// opacityMasks caches the uniform masks used by stamp, keyed by opacity
+// quantized to 16 bits, so hot loops do not allocate a mask per call.
+var opacityMasks sync.Map
 
+// opacityMask returns a shared uniform alpha mask for opacity.
+func opacityMask(opacity float64) *image.Uniform {
+	if opacity < 0 {
+		opacity = 0
+	}
+	if opacity > 1 {
+		opacity = 1
+	}
+	key := uint16(math.Round(opacity * 0xffff))
+	if m, ok := opacityMasks.Load(key); ok {
+		return m.(*image.Uniform)
+	}
+	m, _ := opacityMasks.LoadOrStore(key, image.NewUniform(color.Alpha16{A: key}))
+	return m.(*image.Uniform)
+}
+
+// blenderFor returns the blend function for mode matching the precision of
+// dst.
+func blenderFor(dst image.Image, mode BlendMode) func(base, overlay color.Color, opacity float64) color.Color {
+	switch {
+	case mode == BlendAdditive && is16Bit(dst):
+		return addColors64
+	case mode == BlendAdditive:
+		return addColors
+	case is16Bit(dst):
+		return blendColors64
+	}
+	return blendColors
+}
+
+// newCanvas returns a mutable copy of src to composite onto. 16-bit sources
+// are copied into a 16-bit buffer so pixels the watermark does not touch
+// keep their full precision.
+func newCanvas(src image.Image) draw.Image {
+	return cropCanvas(src, src.Bounds(), false)
+}
+
+// cropCanvas is like newCanvas but copies only the part of src inside r,
+// which must lie within the bounds of src. If gray is set, as reported by
+// keepsGray, a grayscale source is copied into a grayscale canvas.
+func cropCanvas(src image.Image, r image.Rectangle, gray bool) draw.Image {
+	dst := blankCanvas(src, r, gray)
+	copyCanvas(dst, src, r)
 	return dst
 }
 
+// blankCanvas returns a transparent canvas covering r of the type
+// cropCanvas uses for src.
+func blankCanvas(src image.Image, r image.Rectangle, gray bool) draw.Image {
+	if gray {
+		if _, ok := src.(*image.Gray16); ok {
+			return image.NewGray16(r)
+		}
+		return image.NewGray(r)
+	}
+	switch src.(type) {
+	case *image.RGBA64, *image.Gray16:
+		return image.NewRGBA64(r)
+	case *image.NRGBA64:
+		return image.NewNRGBA64(r)
+	}
+	return image.NewRGBA(r)
+}
+
+// copyCanvas copies the part of src inside r into dst, a canvas from
+// blankCanvas for src, without any loss of precision.
+func copyCanvas(dst draw.Image, src image.Image, r image.Rectangle) {
+	switch s := src.(type) {
+	case *image.RGBA64:
+		d := dst.(*image.RGBA64)
+		copyRows(d.Pix[d.PixOffset(r.Min.X, r.Min.Y):], d.Stride, s.Pix[s.PixOffset(r.Min.X, r.Min.Y):], s.Stride, 8*r.Dx(), r.Dy())
+		return
+	case *image.NRGBA64:
+		d := dst.(*image.NRGBA64)
+		copyRows(d.Pix[d.PixOffset(r.Min.X, r.Min.Y):], d.Stride, s.Pix[s.PixOffset(r.Min.X, r.Min.Y):], s.Stride, 8*r.Dx(), r.Dy())
+		return
+	}
+	draw.Draw(dst, r, src, r.Min, draw.Src)
+}
+
+// copyRows copies rows rows of n bytes each between pixel buffers with the
+// given strides.
+func copyRows(dst []uint8, dstStride int, src []uint8, srcStride, n, rows int) {
+	for y := 0; y < rows; y++ {
+		copy(dst[y*dstStride:y*dstStride+n], src[y*srcStride:y*srcStride+n])
+	}
+}
+
+// keepsGray reports whether compositing watermark onto src as opts asks
+// leaves a grayscale source grayscale, so it can be composited in a
+// grayscale canvas a quarter the size. Complement tinting keeps gray
+// watermarks gray, since the complement of a gray is gray, and
+// desaturating makes any watermark gray.
+func keepsGray(src, watermark image.Image, opts Options) bool {
+	switch src.(type) {
+	case *image.Gray, *image.Gray16:
+		return opts.AutoComplementTint || opts.Desaturate || isGray(watermark)
+	}
+	return false
+}
+
+// is16Bit reports whether img stores 16 bits per channel.
+func is16Bit(img image.Image) bool {
+	switch img.ColorModel() {
+	case color.RGBA64Model, color.NRGBA64Model, color.Gray16Model:
+		return true
+	}
+	return false
+}
+
 // blendColors blends two colors with the given opacity for the overlay.
+//
+// Both colors are composited in premultiplied space (Porter-Duff "over"),
+// which is what color.Color.RGBA returns. The overlay covers the base by its
+// own alpha times opacity, so across the {watermark alpha x opacity} matrix:
+//
+//   - alpha 0 leaves the base untouched, whatever the opacity;
+//   - alpha 1 at opacity 1 replaces the base with the overlay color;
+//   - anything else mixes the overlay color (not darkened by its alpha a
+//     second time) with the base in proportion to alpha*opacity.
+//
+// The result alpha follows the same rule, so translucent bases become more
+// opaque where the watermark covers them. Components are rounded to the
+// nearest value rather than truncated.
 func blendColors(base, overlay color.Color, opacity float64) color.Color {
-	br, bg, bb, ba := base.RGBA()
-	or, og, ob, oa := overlay.RGBA()
+	if _, _, _, oa := overlay.RGBA(); oa == 0 {
+		return base
+	}
+	r, g, b, a := blendOver(base, overlay, opacity)
+	return color.RGBA{to8(r), to8(g), to8(b), to8(a)}
+}
 
-	// If watermark pixel is transparent, keep base
-	if oa == 0 {
+// blendColors64 is blendColors at full 16-bit precision, for compositing
+// onto 16-bit canvases.
+func blendColors64(base, overlay color.Color, opacity float64) color.Color {
+	if _, _, _, oa := overlay.RGBA(); oa == 0 {
 		return base
 	}
+	r, g, b, a := blendOver(base, overlay, opacity)
+	return color.RGBA64{to16(r), to16(g), to16(b), to16(a)}
+}
 
-	// Apply opacity to overlay alpha
+// blendOver composites overlay over base with the given opacity and returns
+// the premultiplied result on the 16-bit scale, unrounded.
+func blendOver(base, overlay color.Color, opacity float64) (r, g, b, a float64) {
+	br, bg, bb, ba := base.RGBA()
+	or, og, ob, oa := overlay.RGBA()
+
+	// The overlay is premultiplied by its own alpha already, so only the
+	// opacity scales it; the base shows through what the overlay leaves.
 	overlayAlpha := float64(oa) / 65535.0 * opacity
+	over := func(b, o uint32) float64 {
+		return float64(b)*(1-overlayAlpha) + float64(o)*opacity
+	}
+	return over(br, or), over(bg, og), over(bb, ob), over(ba, oa)
+}
 
-	// Blend
-	r := uint8((float64(br>>8)*(1-overlayAlpha) + float64(or>>8)*overlayAlpha))
-	g := uint8((float64(bg>>8)*(1-overlayAlpha) + float64(og>>8)*overlayAlpha))
-	b := uint8((float64(bb>>8)*(1-overlayAlpha) + float64(ob>>8)*overlayAlpha))
+// addColors adds overlay to base with the given opacity, for BlendAdditive.
+func addColors(base, overlay color.Color, opacity float64) color.Color {
+	r, g, b, a := blendAdd(base, overlay, opacity)
+	return color.RGBA{to8(r), to8(g), to8(b), to8(a)}
+}
 
-	return color.RGBA{r, g, b, uint8(ba >> 8)}
+// addColors64 is addColors at full 16-bit precision.
+func addColors64(base, overlay color.Color, opacity float64) color.Color {
+	r, g, b, a := blendAdd(base, overlay, opacity)
+	return color.RGBA64{to16(r), to16(g), to16(b), to16(a)}
 }
 
-// ApplyFromFiles loads images and applies a watermark.
+// blendAdd adds overlay, scaled by opacity, to base and returns the
+// premultiplied result on the 16-bit scale, unrounded. The sum is clamped
+// to full scale, and color to the result alpha so it stays a valid
+// premultiplied color.
+func blendAdd(base, overlay color.Color, opacity float64) (r, g, b, a float64) {
+	br, bg, bb, ba := base.RGBA()
+	or, og, ob, oa := overlay.RGBA()
+
+	a = math.Min(float64(ba)+float64(oa)*opacity, 0xffff)
+	add := func(b, o uint32) float64 {
+		return math.Min(float64(b)+float64(o)*opacity, a)
+	}
+	return add(br, or), add(bg, og), add(bb, ob), a
+}
+
+// to8 rounds a 16-bit scale component to 8 bits.
+func to8(v float64) uint8 {
+	return uint8(math.Round(v / 257))
+}
+
+// to16 rounds a 16-bit scale component.
+func to16(v float64) uint16 {
+	return uint16(math.Round(v))
+}
+
+// ApplyFromFiles loads images and applies a watermark. If the watermark is a
+// PNG carrying a "wm:offset" text chunk of the form "x,y" and opts.Anchor is
+// nil, the embedded offset is used as the anchor.
 func ApplyFromFiles(srcPath, watermarkPath string, opts Options) (image.Image, error) {
 	srcFile, err := os.Open(srcPath)
 	if err != nil {
@@ -134,50 +572,59 @@ func ApplyFromFiles(srcPath, watermarkPath string, opts Options) (image.Image, e
 		return nil, err
 	}
 
-	wmFile, err := os.Open(watermarkPath)
+	wm, err := loadWatermark(watermarkPath, &opts)
 	if err != nil {
 		return nil, err
 	}
-	defer wmFile.Close()
 
-	wm, _, err := image.Decode(wmFile)
+	return Apply(src, wm, opts), nil
+}
+
+// loadWatermark decodes the watermark at path. If it is a PNG carrying a
+// "wm:offset" text chunk and opts.Anchor is nil, the embedded offset is
+// stored in opts.Anchor.
+func loadWatermark(path string, opts *Options) (image.Image, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return Apply(src, wm, opts), nil
+	wm, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Anchor == nil {
+		if pt, ok := pngOffset(data); ok {
+			opts.Anchor = &pt
+		}
+	}
+	return wm, nil
 }
 
-// Tile applies a watermark in a tiled pattern across the image.
-func Tile(src, watermark image.Image, opacity float64, spacing int) image.Image {
-	srcBounds := src.Bounds()
-	wmBounds := watermark.Bounds()
+// ApplyFromMultipart decodes an uploaded form file and applies a watermark.
+// The file is rewound first, so it may already have been partially read
+// (e.g. for content sniffing).
+func ApplyFromMultipart(file multipart.File, watermark image.Image, opts Options) (image.Image, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return applyFromReader(file, watermark, opts)
+}
 
-	dst := image.NewRGBA(srcBounds)
-	draw.Draw(dst, srcBounds, src, srcBounds.Min, draw.Src)
-
-	wmW := wmBounds.Dx() + spacing
-	wmH := wmBounds.Dy() + spacing
-
-	for y := 0; y < srcBounds.Dy(); y += wmH {
-		for x := 0; x < srcBounds.Dx(); x += wmW {
-			for wy := 0; wy < wmBounds.Dy(); wy++ {
-				for wx := 0; wx < wmBounds.Dx(); wx++ {
-					dx := x + wx
-					dy := y + wy
-					if dx >= srcBounds.Dx() || dy >= srcBounds.Dy() {
-						continue
-					}
-
-					srcColor := dst.At(dx, dy)
-					wmColor := watermark.At(wmBounds.Min.X+wx, wmBounds.Min.Y+wy)
-					blended := blendColors(srcColor, wmColor, opacity)
-					dst.Set(dx, dy, blended)
-				}
-			}
-		}
+// applyFromReader decodes an image from r and applies a watermark.
+func applyFromReader(r io.Reader, watermark image.Image, opts Options) (image.Image, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
 	}
+	return Apply(src, watermark, opts), nil
+}
 
+// Tile applies a watermark in a tiled pattern across the image.
+func Tile(src, watermark image.Image, opacity float64, spacing int) image.Image {
+	dst := newCanvas(src)
+	tile(dst, watermark, opacity, Options{SpacingX: spacing, SpacingY: spacing})
 	return dst
 }
 
@@ -186,10 +633,14 @@ func SaveJPEG(img image.Image, w io.Writer, quality int) error {
 	if quality <= 0 || quality > 100 {
 		quality = 85
 	}
-	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	return observeEncode("jpeg", quality, w, func(w io.Writer) error {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	})
 }
 
 // SavePNG saves the watermarked image as PNG.
 func SavePNG(img image.Image, w io.Writer) error {
-	return png.Encode(w, img)
+	return observeEncode("png", 0, w, func(w io.Writer) error {
+		return png.Encode(w, img)
+	})
 }
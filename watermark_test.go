@@ -0,0 +1,283 @@
+package watermark
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"mime/multipart"
+	"testing"
+)
+
+// fill returns a w x h RGBA image filled with c.
+func fill(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+// rgbaAt returns the 8-bit premultiplied color of img at (x, y).
+func rgbaAt(img image.Image, x, y int) color.RGBA {
+	return color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+}
+
+// changed returns the bounding box of the pixels that differ between a and
+// b, which must have the same bounds.
+func changed(a, b image.Image) image.Rectangle {
+	var r image.Rectangle
+	bounds := a.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if rgbaAt(a, x, y) != rgbaAt(b, x, y) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestApplyFromMultipart(t *testing.T) {
+	var data bytes.Buffer
+	if err := SavePNG(fill(60, 40, color.Black), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Build an upload the way an HTTP handler would receive it.
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("image", "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data.Bytes())
+	mw.Close()
+
+	form, err := multipart.NewReader(&body, mw.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer form.RemoveAll()
+	file, err := form.File["image"][0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// Sniff the content type first, as handlers often do.
+	file.Read(make([]byte, 512))
+
+	out, err := ApplyFromMultipart(file, fill(10, 10, color.White), Options{Position: TopLeft, Opacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Bounds(); got != image.Rect(0, 0, 60, 40) {
+		t.Errorf("bounds = %v, want 60x40", got)
+	}
+	if c := rgbaAt(out, 5, 5); c.R != 255 {
+		t.Errorf("watermark pixel = %v, want white", c)
+	}
+}
+
+func TestApply16BitUntouched(t *testing.T) {
+	src := image.NewRGBA64(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			// Low bytes that 8-bit precision would lose.
+			src.SetRGBA64(x, y, color.RGBA64{uint16(x*1000 + 1), uint16(y*2000 + 3), 0x1235, 0xffff})
+		}
+	}
+	var in bytes.Buffer
+	if err := png.Encode(&in, src); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decoded.(*image.RGBA64); !ok {
+		t.Fatalf("decoded %T, want a 16-bit image", decoded)
+	}
+
+	opts := Options{Position: TopLeft, Opacity: 1}
+	var out bytes.Buffer
+	if err := png.Encode(&out, Apply(decoded, fill(10, 10, color.White), opts)); err != nil {
+		t.Fatal(err)
+	}
+	result, err := png.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			if x < 10 && y < 10 {
+				continue
+			}
+			if got, want := result.At(x, y), decoded.At(x, y); got != want {
+				t.Fatalf("untouched pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+	if c := rgbaAt(result, 5, 5); c.R != 255 {
+		t.Errorf("watermark pixel = %v, want white", c)
+	}
+}
+
+func TestBlendPremultipliedSource(t *testing.T) {
+	// A half-transparent red source pixel, premultiplied, under an opaque
+	// blue watermark at half opacity.
+	base := color.RGBA{100, 0, 0, 128}
+	overlay := color.NRGBA{0, 0, 255, 255}
+
+	want := color.RGBA{50, 0, 128, 192}
+	if got := blendColors(base, overlay, 0.5); got != want {
+		t.Errorf("blendColors = %v, want %v", got, want)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(src, src.Bounds(), image.NewUniform(base), image.Point{}, draw.Src)
+	wm := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(wm, wm.Bounds(), image.NewUniform(overlay), image.Point{}, draw.Src)
+	out := Apply(src, wm, Options{Position: TopLeft, Opacity: 0.5})
+	if got := rgbaAt(out, 1, 1); got != want {
+		t.Errorf("Apply = %v, want %v", got, want)
+	}
+}
+
+func TestBlendMatrix(t *testing.T) {
+	base := color.RGBA{0, 0, 0, 255}
+	for _, alpha := range []uint8{0, 1, 64, 128, 255} {
+		for _, opacity := range []float64{0.25, 0.5, 1} {
+			overlay := color.NRGBA{255, 255, 255, alpha}
+			// White over black shows as alpha*opacity gray, never darkened
+			// by the alpha a second time.
+			v := uint8(math.Round(float64(alpha) * opacity))
+			want := color.RGBA{v, v, v, 255}
+
+			if got := blendColors(base, overlay, opacity); got != want {
+				t.Errorf("alpha %d, opacity %v: blendColors = %v, want %v", alpha, opacity, got, want)
+			}
+			v16 := uint16(math.Round(float64(alpha) * 257 * opacity))
+			want64 := color.RGBA64{v16, v16, v16, 0xffff}
+			if got := color.RGBA64Model.Convert(blendColors64(base, overlay, opacity)); got != want64 {
+				t.Errorf("alpha %d, opacity %v: blendColors64 = %v, want %v", alpha, opacity, got, want64)
+			}
+
+			// The draw.DrawMask fast path agrees to within rounding.
+			wm := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+			draw.Draw(wm, wm.Bounds(), image.NewUniform(overlay), image.Point{}, draw.Src)
+			c := rgbaAt(Apply(fill(2, 2, base), wm, Options{Position: TopLeft, Opacity: opacity}), 0, 0)
+			if d := int(c.R) - int(v); d < -1 || d > 1 || c.A != 255 {
+				t.Errorf("alpha %d, opacity %v: Apply = %v, want %v", alpha, opacity, c, want)
+			}
+		}
+	}
+}
+
+func TestBlendTranslucentBase(t *testing.T) {
+	// Where the watermark covers a translucent base, the result becomes
+	// more opaque: 0.5 + 0.5*(1-0.5) = 0.75.
+	got := blendColors(color.RGBA{0, 0, 0, 128}, color.NRGBA{255, 255, 255, 255}, 0.5)
+	if want := (color.RGBA{128, 128, 128, 192}); got != want {
+		t.Errorf("blendColors = %v, want %v", got, want)
+	}
+}
+
+func TestAlphaThreshold(t *testing.T) {
+	// A logo with a faint alpha=3 halo around an opaque core.
+	wm := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(wm, wm.Bounds(), image.NewUniform(color.NRGBA{255, 255, 255, 3}), image.Point{}, draw.Src)
+	draw.Draw(wm, image.Rect(3, 3, 7, 7), image.NewUniform(color.NRGBA{255, 255, 255, 255}), image.Point{}, draw.Src)
+	src := fill(10, 10, color.Black)
+	opts := Options{Position: TopLeft, Opacity: 1}
+
+	if r := changed(src, Apply(src, wm, opts)); r != wm.Bounds() {
+		t.Fatalf("without threshold: changed %v, want the whole halo", r)
+	}
+	opts.AlphaThreshold = 5
+	if r := changed(src, Apply(src, wm, opts)); r != image.Rect(3, 3, 7, 7) {
+		t.Errorf("threshold 5: changed %v, want only the core", r)
+	}
+}
+
+func TestApplyRGBA(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	out := ApplyRGBA(src, fill(5, 5, color.White), Options{Position: TopLeft, Opacity: 1})
+
+	// The result can be drawn on directly.
+	var dst draw.Image = out
+	dst.Set(19, 19, color.RGBA{255, 0, 0, 255})
+	if c := out.RGBAAt(19, 19); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("pixel after Set = %v, want red", c)
+	}
+	if c := out.RGBAAt(2, 2); c.R != 255 {
+		t.Errorf("watermark pixel = %v, want white", c)
+	}
+}
+
+func TestOpacityMaskCached(t *testing.T) {
+	wm := photo(16, 16)
+	for _, opacity := range []float64{0, 0.3, 0.5, 1} {
+		m := opacityMask(opacity)
+		if again := opacityMask(opacity); again != m {
+			t.Errorf("opacity %v: second call returned a new mask", opacity)
+		}
+
+		// A cached mask draws exactly what a freshly built one does.
+		fresh := image.NewUniform(color.Alpha16{A: uint16(math.Round(opacity * 0xffff))})
+		cached, built := fill(16, 16, color.Black), fill(16, 16, color.Black)
+		draw.DrawMask(cached, cached.Bounds(), wm, image.Point{}, m, image.Point{}, draw.Over)
+		draw.DrawMask(built, built.Bounds(), wm, image.Point{}, fresh, image.Point{}, draw.Over)
+		if r := changed(cached, built); !r.Empty() {
+			t.Errorf("opacity %v: cached and fresh masks differ in %v", opacity, r)
+		}
+	}
+}
+
+func BenchmarkStampMask(b *testing.B) {
+	dst, wm := fill(64, 64, color.Black), photo(8, 8)
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			draw.DrawMask(dst, wm.Bounds(), wm, image.Point{}, opacityMask(0.5), image.Point{}, draw.Over)
+		}
+	})
+	b.Run("fresh", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mask := image.NewUniform(color.Alpha16{A: 0x8000})
+			draw.DrawMask(dst, wm.Bounds(), wm, image.Point{}, mask, image.Point{}, draw.Over)
+		}
+	})
+}
+
+func TestBlendAdditive(t *testing.T) {
+	base := color.RGBA{100, 200, 30, 255}
+	overlay := color.NRGBA{120, 120, 120, 255}
+	src := fill(4, 4, base)
+	wm := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(wm, wm.Bounds(), image.NewUniform(overlay), image.Point{}, draw.Src)
+
+	for _, opacity := range []float64{0.5, 1} {
+		out := Apply(src, wm, Options{Position: TopLeft, Opacity: opacity, BlendMode: BlendAdditive})
+		got := rgbaAt(out, 1, 1)
+		sum := func(b, o uint8) uint8 {
+			return uint8(math.Min(math.Round(float64(b)+float64(o)*opacity), 255))
+		}
+		want := color.RGBA{sum(base.R, overlay.R), sum(base.G, overlay.G), sum(base.B, overlay.B), 255}
+		if got != want {
+			t.Errorf("opacity %v: pixel = %v, want %v", opacity, got, want)
+		}
+
+		// 16-bit sources add at full precision and clamp the same way.
+		src16 := image.NewRGBA64(src.Bounds())
+		draw.Draw(src16, src16.Bounds(), src, image.Point{}, draw.Src)
+		out16 := Apply(src16, wm, Options{Position: TopLeft, Opacity: opacity, BlendMode: BlendAdditive})
+		if got := rgbaAt(out16, 1, 1); got != want {
+			t.Errorf("opacity %v: 16-bit pixel = %v, want %v", opacity, got, want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+//go:build webp
+// +build webp
+
+package watermark
+
+import (
+	"image"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// SaveWebP saves the watermarked image as lossy WebP. It is only available
+// when built with the webp tag, which requires cgo.
+func SaveWebP(img image.Image, w io.Writer, quality int) error {
+	if quality <= 0 || quality > 100 {
+		quality = 85
+	}
+	return observeEncode("webp", quality, w, func(w io.Writer) error {
+		return webp.Encode(w, img, &webp.Options{Quality: float32(quality)})
+	})
+}
+
+// SaveWebPLossless saves the watermarked image as lossless WebP, preserving
+// alpha. It is only available when built with the webp tag.
+func SaveWebPLossless(img image.Image, w io.Writer) error {
+	return observeEncode("webp", 0, w, func(w io.Writer) error {
+		return webp.Encode(w, img, &webp.Options{Lossless: true, Exact: true})
+	})
+}
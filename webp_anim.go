@@ -0,0 +1,195 @@
+//go:build webp
+// +build webp
+
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+
+	"github.com/chai2010/webp"
+)
+
+// ApplyAnimatedWebP watermarks every frame of an animated WebP and returns
+// the re-encoded animation. Frames are composited onto the canvas as a
+// player would show them, watermarked whole and written back losslessly as
+// full-canvas frames, keeping each frame's duration, the loop count and
+// the background color. Data that is not an animated WebP returns
+// ErrNotAnimatedWebP. It is only available when built with the webp tag.
+func ApplyAnimatedWebP(data []byte, watermark image.Image, opts Options) ([]byte, error) {
+	chunks, ok := riffChunks(data)
+	if !ok {
+		return nil, ErrNotAnimatedWebP
+	}
+
+	var size image.Point
+	var anim []byte
+	for _, c := range chunks {
+		switch c.id {
+		case "VP8X":
+			if len(c.data) >= 10 {
+				size = image.Pt(get24(c.data[4:])+1, get24(c.data[7:])+1)
+			}
+		case "ANIM":
+			anim = c.data
+		}
+	}
+	if anim == nil || len(anim) < 6 || size.X <= 0 || size.Y <= 0 {
+		return nil, ErrNotAnimatedWebP
+	}
+
+	canvas := image.NewNRGBA(image.Rectangle{Max: size})
+	var frames []byte
+	var prevRect image.Rectangle
+	prevDispose := false
+	for _, c := range chunks {
+		if c.id != "ANMF" {
+			continue
+		}
+		if len(c.data) < 16 {
+			return nil, ErrNotAnimatedWebP
+		}
+		min := image.Pt(2*get24(c.data[0:]), 2*get24(c.data[3:]))
+		r := image.Rectangle{Min: min, Max: min.Add(image.Pt(get24(c.data[6:])+1, get24(c.data[9:])+1))}
+		duration := c.data[12:15]
+		blend := c.data[15]&0x02 == 0
+		dispose := c.data[15]&0x01 != 0
+
+		if prevDispose {
+			draw.Draw(canvas, prevRect, image.Transparent, image.Point{}, draw.Src)
+		}
+		img, err := decodeWebPFrame(c.data[16:], r.Size())
+		if err != nil {
+			return nil, err
+		}
+		op := draw.Src
+		if blend {
+			op = draw.Over
+		}
+		draw.Draw(canvas, r, img, image.Point{}, op)
+		prevRect, prevDispose = r, dispose
+
+		frame, err := encodeWebPFrame(Apply(canvas, watermark, opts))
+		if err != nil {
+			return nil, err
+		}
+		// A full-canvas frame at the origin that replaces, rather than
+		// blends with, what came before.
+		hdr := make([]byte, 16)
+		put24(hdr[6:], size.X-1)
+		put24(hdr[9:], size.Y-1)
+		copy(hdr[12:15], duration)
+		hdr[15] = 0x02
+		frames = appendChunk(frames, "ANMF", append(hdr, frame...))
+	}
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x10 | 0x02 // alpha, animation
+	put24(vp8x[4:], size.X-1)
+	put24(vp8x[7:], size.Y-1)
+
+	var body []byte
+	body = appendChunk(body, "VP8X", vp8x)
+	body = appendChunk(body, "ANIM", anim[:6])
+	body = append(body, frames...)
+	return riffFile(body), nil
+}
+
+// decodeWebPFrame decodes the image chunks of an animation frame by
+// wrapping them in a still WebP file.
+func decodeWebPFrame(data []byte, size image.Point) (*image.NRGBA, error) {
+	body := data
+	if bytes.HasPrefix(data, []byte("ALPH")) {
+		// A separate alpha chunk is only read from an extended file.
+		vp8x := make([]byte, 10)
+		vp8x[0] = 0x10
+		put24(vp8x[4:], size.X-1)
+		put24(vp8x[7:], size.Y-1)
+		body = append(appendChunk(nil, "VP8X", vp8x), data...)
+	}
+	m, err := webp.DecodeRGBA(riffFile(body))
+	if err != nil {
+		return nil, err
+	}
+	// libwebp returns unpremultiplied pixels.
+	return &image.NRGBA{Pix: m.Pix, Stride: m.Stride, Rect: m.Rect}, nil
+}
+
+// encodeWebPFrame losslessly encodes img and returns its image chunks, for
+// use as the payload of an animation frame.
+func encodeWebPFrame(img image.Image) ([]byte, error) {
+	n := ToNRGBA(img)
+	// libwebp expects unpremultiplied pixels.
+	data, err := webp.EncodeExactLosslessRGBA(&image.RGBA{Pix: n.Pix, Stride: n.Stride, Rect: n.Rect})
+	if err != nil {
+		return nil, err
+	}
+	chunks, ok := riffChunks(data)
+	if !ok {
+		return nil, ErrNotAnimatedWebP
+	}
+	var out []byte
+	for _, c := range chunks {
+		if c.id != "VP8X" {
+			out = appendChunk(out, c.id, c.data)
+		}
+	}
+	return out, nil
+}
+
+// riffChunk is one chunk of a RIFF file.
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// riffChunks splits a RIFF WEBP file into its chunks.
+func riffChunks(data []byte) ([]riffChunk, bool) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, false
+	}
+	var chunks []riffChunk
+	for p := 12; p+8 <= len(data); {
+		n := int(binary.LittleEndian.Uint32(data[p+4:]))
+		if n < 0 || p+8+n > len(data) {
+			return nil, false
+		}
+		chunks = append(chunks, riffChunk{string(data[p : p+4]), data[p+8 : p+8+n]})
+		p += 8 + n + n&1
+	}
+	return chunks, true
+}
+
+// appendChunk appends a RIFF chunk holding payload to dst, padded to an
+// even length.
+func appendChunk(dst []byte, id string, payload []byte) []byte {
+	var hdr [8]byte
+	copy(hdr[:4], id)
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	dst = append(append(dst, hdr[:]...), payload...)
+	if len(payload)&1 == 1 {
+		dst = append(dst, 0)
+	}
+	return dst
+}
+
+// riffFile wraps chunks in a RIFF WEBP file header.
+func riffFile(chunks []byte) []byte {
+	out := make([]byte, 12, 12+len(chunks))
+	copy(out, "RIFF")
+	binary.LittleEndian.PutUint32(out[4:], uint32(4+len(chunks)))
+	copy(out[8:], "WEBP")
+	return append(out, chunks...)
+}
+
+// get24 reads a 24-bit little-endian value.
+func get24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+// put24 writes a 24-bit little-endian value.
+func put24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
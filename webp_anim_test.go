@@ -0,0 +1,96 @@
+//go:build webp
+// +build webp
+
+package watermark
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// animatedWebP builds an animated WebP of w x h full-canvas frames in the
+// given colors, shown for the given durations in milliseconds and looping
+// loops times.
+func animatedWebP(t *testing.T, w, h int, colors []color.Color, durations []int, loops int) []byte {
+	t.Helper()
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x10 | 0x02
+	put24(vp8x[4:], w-1)
+	put24(vp8x[7:], h-1)
+	anim := make([]byte, 6)
+	binary.LittleEndian.PutUint16(anim[4:], uint16(loops))
+
+	body := appendChunk(nil, "VP8X", vp8x)
+	body = appendChunk(body, "ANIM", anim)
+	for i, c := range colors {
+		frame, err := encodeWebPFrame(fill(w, h, c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdr := make([]byte, 16)
+		put24(hdr[6:], w-1)
+		put24(hdr[9:], h-1)
+		put24(hdr[12:], durations[i])
+		hdr[15] = 0x02
+		body = appendChunk(body, "ANMF", append(hdr, frame...))
+	}
+	return riffFile(body)
+}
+
+func TestApplyAnimatedWebP(t *testing.T) {
+	colors := []color.Color{color.RGBA{200, 0, 0, 255}, color.RGBA{0, 200, 0, 255}, color.RGBA{0, 0, 200, 255}}
+	durations := []int{100, 250, 40}
+	data := animatedWebP(t, 48, 32, colors, durations, 3)
+
+	out, err := ApplyAnimatedWebP(data, fill(8, 8, color.White), Options{Position: TopLeft, Opacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, ok := riffChunks(out)
+	if !ok {
+		t.Fatal("output is not a RIFF WEBP file")
+	}
+
+	var frames []riffChunk
+	for _, c := range chunks {
+		switch c.id {
+		case "ANIM":
+			if loops := binary.LittleEndian.Uint16(c.data[4:]); loops != 3 {
+				t.Errorf("loop count %d, want 3", loops)
+			}
+		case "ANMF":
+			frames = append(frames, c)
+		}
+	}
+	if len(frames) != len(durations) {
+		t.Fatalf("%d frames, want %d", len(frames), len(durations))
+	}
+	for i, f := range frames {
+		if d := get24(f.data[12:]); d != durations[i] {
+			t.Errorf("frame %d: duration %dms, want %dms", i, d, durations[i])
+		}
+		img, err := decodeWebPFrame(f.data[16:], image.Pt(48, 32))
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if c := rgbaAt(img, 4, 4); c != (color.RGBA{255, 255, 255, 255}) {
+			t.Errorf("frame %d: watermark pixel %v, want white", i, c)
+		}
+		if c, want := rgbaAt(img, 40, 20), colors[i]; c != want {
+			t.Errorf("frame %d: background %v, want %v", i, c, want)
+		}
+	}
+}
+
+func TestApplyAnimatedWebPStill(t *testing.T) {
+	var still bytes.Buffer
+	if err := SaveWebPLossless(fill(8, 8, color.White), &still); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ApplyAnimatedWebP(still.Bytes(), fill(4, 4, color.White), DefaultOptions()); err != ErrNotAnimatedWebP {
+		t.Errorf("still image: err = %v, want ErrNotAnimatedWebP", err)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !webp
+// +build !webp
+
+package watermark
+
+import (
+	"image"
+	"io"
+)
+
+// SaveWebP saves the watermarked image as lossy WebP. This build lacks the
+// webp tag, so it always returns ErrWebPUnsupported.
+func SaveWebP(img image.Image, w io.Writer, quality int) error {
+	return ErrWebPUnsupported
+}
+
+// SaveWebPLossless saves the watermarked image as lossless WebP. This build
+// lacks the webp tag, so it always returns ErrWebPUnsupported.
+func SaveWebPLossless(img image.Image, w io.Writer) error {
+	return ErrWebPUnsupported
+}
+
+// ApplyAnimatedWebP watermarks every frame of an animated WebP. This build
+// lacks the webp tag, so it always returns ErrWebPUnsupported.
+func ApplyAnimatedWebP(data []byte, watermark image.Image, opts Options) ([]byte, error) {
+	return nil, ErrWebPUnsupported
+}
@@ -0,0 +1,30 @@
+//go:build !webp
+// +build !webp
+
+package watermark
+
+import (
+	"bytes"
+	"image/color"
+	"testing"
+)
+
+func TestSaveWebPUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SaveWebP(fill(4, 4, color.White), &buf, 90); err != ErrWebPUnsupported {
+		t.Errorf("SaveWebP error = %v, want ErrWebPUnsupported", err)
+	}
+}
+
+func TestSaveWebPLosslessUnsupported(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SaveWebPLossless(fill(4, 4, color.White), &buf); err != ErrWebPUnsupported {
+		t.Errorf("SaveWebPLossless error = %v, want ErrWebPUnsupported", err)
+	}
+}
+
+func TestApplyAnimatedWebPUnsupported(t *testing.T) {
+	if _, err := ApplyAnimatedWebP(nil, fill(4, 4, color.White), DefaultOptions()); err != ErrWebPUnsupported {
+		t.Errorf("ApplyAnimatedWebP error = %v, want ErrWebPUnsupported", err)
+	}
+}
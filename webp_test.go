@@ -0,0 +1,58 @@
+//go:build webp
+// +build webp
+
+package watermark
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/chai2010/webp"
+)
+
+func TestSaveWebP(t *testing.T) {
+	img := Apply(fill(64, 48, color.RGBA{0, 0, 200, 255}), fill(16, 16, color.White), DefaultOptions())
+
+	var buf bytes.Buffer
+	if err := SaveWebP(img, &buf, 90); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	if got := decoded.Bounds(); got != img.Bounds() {
+		t.Errorf("decoded bounds = %v, want %v", got, img.Bounds())
+	}
+	if c := rgbaAt(decoded, 5, 5); c.B < 180 || c.R > 20 {
+		t.Errorf("decoded background = %v, want about blue", c)
+	}
+}
+
+func TestSaveWebPLossless(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 16), uint8(y * 16), 200, uint8(x * y)})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := SaveWebPLossless(img, &buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decoding output: %v", err)
+	}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+			if want := img.NRGBAAt(x, y); got.A != want.A || (want.A == 255 && got != want) {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}